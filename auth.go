@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+)
+
+// リフレッシュトークンが失効・取り消しされたことによるエラーかどうかを判定
+func isInvalidGrant(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return strings.Contains(string(retrieveErr.Body), "invalid_grant")
+	}
+	return err != nil && strings.Contains(err.Error(), "invalid_grant")
+}
+
+// 保存済みのトークンに必要なスコープ（-share の Drive など）が含まれていないことによる 403 エラーかどうかを判定
+// スコープを追加しても既存の token.json は更新されないため、再認証が必要になる
+func isInsufficientScope(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusForbidden {
+		return false
+	}
+	for _, e := range apiErr.Errors {
+		if e.Reason == "insufficientPermissions" {
+			return true
+		}
+	}
+	return strings.Contains(strings.ToLower(apiErr.Message), "insufficient authentication scopes")
+}
+
+// 再認証が必要なことを案内し、reauth が指定されていれば古いトークンファイルを削除
+func reportInvalidGrant(tokFile string, reauth bool) {
+	fmt.Fprintf(os.Stderr, "The saved authorization in %s has expired or been revoked.\n", tokFile)
+	removeStaleToken(tokFile, reauth)
+}
+
+// 保存済みのトークンのスコープが足りないことを案内し、reauth が指定されていれば古いトークンファイルを削除
+func reportInsufficientScope(tokFile string, reauth bool) {
+	fmt.Fprintf(os.Stderr, "The saved authorization in %s does not include the permissions this run needs (e.g. Drive access for -share).\n", tokFile)
+	removeStaleToken(tokFile, reauth)
+}
+
+// reauth が指定されていればトークンファイルを削除し、指定されていなければ -reauth を案内
+func removeStaleToken(tokFile string, reauth bool) {
+	if !reauth {
+		fmt.Fprintln(os.Stderr, "Re-run with -reauth to delete it, then run again to re-authorize.")
+		return
+	}
+
+	if err := os.Remove(tokFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Unable to delete %s: %v\n", tokFile, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Deleted %s. Run again to re-authorize.\n", tokFile)
+}
+
+// クライアントの認証情報のファイル
+const credentialsFile = "credentials.json"
+
+// ファイルがない場合に認証情報の JSON をそのまま読み込む環境変数
+const credentialsEnvVar = "GOOGLE_SHEETS_CREDENTIALS"
+
+// 認証情報を credentials.json から読み込む（ファイルがないか空なら環境変数 GOOGLE_SHEETS_CREDENTIALS の JSON）
+// コンテナでシークレットを環境変数として渡す場合に使う
+// リポジトリには空の credentials.json が置かれているため、空のファイルはないものとして扱う
+func readCredentials() ([]byte, error) {
+	b, err := os.ReadFile(credentialsFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) > 0 {
+		return b, nil
+	}
+
+	inline, ok := os.LookupEnv(credentialsEnvVar)
+	if !ok || strings.TrimSpace(inline) == "" {
+		return nil, fmt.Errorf("%s is missing or empty and %s is not set", credentialsFile, credentialsEnvVar)
+	}
+	if !json.Valid([]byte(inline)) {
+		return nil, fmt.Errorf("%s does not contain valid JSON", credentialsEnvVar)
+	}
+	debugf("reading credentials from %s", credentialsEnvVar)
+	return []byte(inline), nil
+}
+
+// credentials.json に登録されているリダイレクトURIの一覧
+func registeredRedirectURIs(credentials []byte) ([]string, error) {
+	var c struct {
+		Installed *struct {
+			RedirectURIs []string `json:"redirect_uris"`
+		} `json:"installed"`
+		Web *struct {
+			RedirectURIs []string `json:"redirect_uris"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(credentials, &c); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c.Installed != nil:
+		return c.Installed.RedirectURIs, nil
+	case c.Web != nil:
+		return c.Web.RedirectURIs, nil
+	}
+	return nil, fmt.Errorf("credentials have neither an installed nor a web client")
+}
+
+// ローカルのリダイレクト先ポートに一致する登録済みのリダイレクトURIを探し、そのホスト（localhost か 127.0.0.1）を返す
+// ポートなしで登録されたループバックのURI（http://localhost など）は任意のポートを受け付ける
+// リダイレクトURIは完全一致で照合されるため、返したホストを待ち受けと RedirectURL の両方に使う
+func redirectHost(registered []string, port int) (string, error) {
+	for _, raw := range registered {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme != "http" {
+			continue
+		}
+		host := u.Hostname()
+		if host != "localhost" && host != "127.0.0.1" {
+			continue
+		}
+		if u.Port() == "" || (port != 0 && u.Port() == strconv.Itoa(port)) {
+			return host, nil
+		}
+	}
+
+	if port == 0 {
+		return "", fmt.Errorf("an ephemeral -auth-port needs a registered loopback redirect URI without a port (e.g. http://localhost); registered: %v", registered)
+	}
+	return "", fmt.Errorf("no registered loopback redirect URI accepts port %d; registered: %v", port, registered)
+}
+
+// リダイレクトを受け取るためにループバックアドレスで待ち受ける
+// localhost はブラウザによって 127.0.0.1 と ::1 のどちらにも解決されるため、両方で同じポートを待ち受ける
+func listenLoopback(host string, port int) ([]net.Listener, error) {
+	first, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	listeners := []net.Listener{first}
+	if host != "localhost" {
+		return listeners, nil
+	}
+
+	boundPort := strconv.Itoa(first.Addr().(*net.TCPAddr).Port)
+	if l, err := net.Listen("tcp", net.JoinHostPort("::1", boundPort)); err == nil {
+		listeners = append(listeners, l)
+	} else {
+		debugf("not listening on [::1]:%s: %v", boundPort, err)
+	}
+	return listeners, nil
+}
+
+// 認証リクエストごとに作る state と PKCE の値
+// state はリダイレクトが自分の開始した認証のものかの確認に、code_verifier は認証コードの横取り対策に使う
+type authSession struct {
+	state    string
+	verifier string
+}
+
+// 認証URLを標準エラー出力に表示
+// トークンの取得にはURLを開く必要があるため、-quiet や非対話的な実行でも省略しない
+// 標準出力は -output json などの結果に使うため、混ざらないよう標準エラー出力に書く
+func printAuthURL(instruction string, authURL string) {
+	fmt.Fprintf(os.Stderr, "%s\n%v\n", instruction, authURL)
+}
+
+// ランダムな URL セーフの文字列を作成（n はバイト数）
+func randomURLSafe(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// 新しい state と code_verifier を作成
+func newAuthSession() (*authSession, error) {
+	state, err := randomURLSafe(24)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate state: %w", err)
+	}
+	// 32 バイトで RFC 7636 が求める 43 文字以上になる
+	verifier, err := randomURLSafe(32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate code verifier: %w", err)
+	}
+	return &authSession{state: state, verifier: verifier}, nil
+}
+
+// state と code_challenge（S256）を付けた認証URLを作成
+func (s *authSession) authCodeURL(config *oauth2.Config) string {
+	sum := sha256.Sum256([]byte(s.verifier))
+	return config.AuthCodeURL(s.state, oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:])),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+}
+
+// リダイレクトの state が認証を開始したときのものと一致するかを検証
+func (s *authSession) verifyState(state string) error {
+	if subtle.ConstantTimeCompare([]byte(state), []byte(s.state)) != 1 {
+		return fmt.Errorf("authorization state mismatch; the redirect was not started by this run")
+	}
+	return nil
+}
+
+// code_verifier を付けて認証コードをトークンと交換
+func (s *authSession) exchange(ctx context.Context, config *oauth2.Config, authCode string) (*oauth2.Token, error) {
+	return config.Exchange(ctx, authCode, oauth2.SetAuthURLParam("code_verifier", s.verifier))
+}
+
+// ローカルでリダイレクトを受け取るサーバーを起動して認証コードを取得し、取得したトークンを返す
+// host は redirectHost で得た登録済みのホストで、port が0の場合は空いているポートを使う
+func getTokenFromLocalServer(ctx context.Context, config *oauth2.Config, host string, port int) *oauth2.Token {
+	listeners, err := listenLoopback(host, port)
+	if err != nil {
+		log.Fatalf("Unable to listen for the authorization redirect: %v", err)
+	}
+
+	redirectConfig := *config
+	redirectConfig.RedirectURL = fmt.Sprintf("http://%s:%d", host, listeners[0].Addr().(*net.TCPAddr).Port)
+
+	session, err := newAuthSession()
+	if err != nil {
+		log.Fatalf("Unable to start authorization: %v", err)
+	}
+	printAuthURL("Go to the following link in your browser to authorize this app:", session.authCodeURL(&redirectConfig))
+
+	authCode, err := waitForAuthCode(ctx, session, listeners)
+	if err != nil {
+		log.Fatalf("Unable to retrieve authorization code: %v", err)
+	}
+
+	tok, err := session.exchange(ctx, &redirectConfig, authCode)
+	if err != nil {
+		log.Fatalf("Unable to retrieve token from web: %v", err)
+	}
+	return tok
+}
+
+// listeners でリダイレクトを受け取り、このセッションの state を持つ最初の認証コードを返す
+// state が一致しないリクエスト（他のタブや別のプロセスからのもの）には 400 を返して待ち続け、ctx が終わるとそのエラーを返す
+func waitForAuthCode(ctx context.Context, session *authSession, listeners []net.Listener) (string, error) {
+	// 最初の結果だけを使う。2回目以降のリダイレクト（再読み込みなど）でハンドラーが止まらないよう、送信は待たない
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			if err := session.verifyState(q.Get("state")); err != nil {
+				debugf("ignoring authorization redirect: %v", err)
+				http.Error(w, "Authorization state mismatch", http.StatusBadRequest)
+				return
+			}
+			if e := q.Get("error"); e != "" {
+				http.Error(w, "Authorization failed: "+e, http.StatusBadRequest)
+				select {
+				case errCh <- fmt.Errorf("authorization failed: %s", e):
+				default:
+				}
+				return
+			}
+			code := q.Get("code")
+			if code == "" {
+				http.Error(w, "Missing authorization code", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(w, "Authorization complete. You can close this window.")
+			select {
+			case codeCh <- code:
+			default:
+			}
+		}),
+	}
+	for _, listener := range listeners {
+		go server.Serve(listener)
+	}
+	defer server.Close()
+
+	select {
+	case code := <-codeCh:
+		return code, nil
+	case err := <-errCh:
+		return "", err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
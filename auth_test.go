@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// 一時ディレクトリに移動し、credentials.json の内容と環境変数を設定して readCredentials を呼ぶ
+func readCredentialsIn(t *testing.T, file *string, env *string) ([]byte, error) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if file != nil {
+		if err := os.WriteFile(credentialsFile, []byte(*file), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if env != nil {
+		t.Setenv(credentialsEnvVar, *env)
+	} else {
+		t.Setenv(credentialsEnvVar, "")
+		os.Unsetenv(credentialsEnvVar)
+	}
+	return readCredentials()
+}
+
+func TestReadCredentials(t *testing.T) {
+	str := func(s string) *string { return &s }
+	fileJSON := `{"installed":{"client_id":"file"}}`
+	envJSON := `{"installed":{"client_id":"env"}}`
+
+	tests := []struct {
+		name    string
+		file    *string
+		env     *string
+		want    string
+		wantErr string
+	}{
+		{name: "file", file: str(fileJSON), env: str(envJSON), want: fileJSON},
+		{name: "missing file", env: str(envJSON), want: envJSON},
+		{name: "empty file", file: str(""), env: str(envJSON), want: envJSON},
+		{name: "blank file", file: str(" \n"), env: str(envJSON), want: envJSON},
+		{name: "empty file without env", file: str(""), wantErr: "is missing or empty"},
+		{name: "invalid env", env: str("{"), wantErr: "does not contain valid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readCredentialsIn(t, tt.file, tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readCredentials() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readCredentials(): %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("readCredentials() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirectHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		registered []string
+		port       int
+		want       string
+		wantErr    bool
+	}{
+		{name: "localhost without port", registered: []string{"http://localhost"}, port: 8080, want: "localhost"},
+		{name: "ephemeral port", registered: []string{"http://localhost"}, port: 0, want: "localhost"},
+		{name: "127.0.0.1 with matching port", registered: []string{"http://127.0.0.1:8080"}, port: 8080, want: "127.0.0.1"},
+		{name: "127.0.0.1 without port", registered: []string{"urn:ietf:wg:oauth:2.0:oob", "http://127.0.0.1"}, port: 9000, want: "127.0.0.1"},
+		{name: "first match wins", registered: []string{"http://127.0.0.1:8080", "http://localhost"}, port: 8080, want: "127.0.0.1"},
+		{name: "port mismatch", registered: []string{"http://127.0.0.1:8080"}, port: 9000, wantErr: true},
+		{name: "ephemeral port needs portless URI", registered: []string{"http://localhost:8080"}, port: 0, wantErr: true},
+		{name: "not loopback", registered: []string{"http://example.com"}, port: 8080, wantErr: true},
+		{name: "https", registered: []string{"https://localhost"}, port: 8080, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := redirectHost(tt.registered, tt.port)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: redirectHost = %q, want error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: redirectHost = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+// localhost の場合は IPv4 と IPv6 の両方を同じポートで待ち受ける（IPv6 が使えない環境では IPv4 だけ）
+func TestListenLoopback(t *testing.T) {
+	listeners, err := listenLoopback("localhost", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, l := range listeners {
+			l.Close()
+		}
+	}()
+	port := listeners[0].Addr().(*net.TCPAddr).Port
+	for _, l := range listeners {
+		if got := l.Addr().(*net.TCPAddr).Port; got != port {
+			t.Errorf("listener %v uses port %d, want %d", l.Addr(), got, port)
+		}
+	}
+
+	only, err := listenLoopback("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer only[0].Close()
+	if len(only) != 1 {
+		t.Errorf("127.0.0.1 opened %d listeners, want 1", len(only))
+	}
+}
+
+func TestIsInsufficientScope(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "reason", err: &googleapi.Error{Code: 403, Message: "Request had insufficient authentication scopes.", Errors: []googleapi.ErrorItem{{Reason: "insufficientPermissions"}}}, want: true},
+		{name: "message only", err: fmt.Errorf("share: %w", &googleapi.Error{Code: 403, Message: "Request had insufficient authentication scopes."}), want: true},
+		{name: "no access to file", err: &googleapi.Error{Code: 403, Message: "The caller does not have permission", Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}, want: false},
+		{name: "not found", err: &googleapi.Error{Code: 404, Message: "File not found"}, want: false},
+		{name: "other error", err: errors.New("insufficient authentication scopes"), want: false},
+		{name: "nil", err: nil, want: false},
+	}
+	for _, tt := range tests {
+		if got := isInsufficientScope(tt.err); got != tt.want {
+			t.Errorf("%s: isInsufficientScope = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// state が一致しないリダイレクトには 400 を返して待ち続け、正しい state の認証コードを受け取る
+func TestWaitForAuthCodeIgnoresWrongState(t *testing.T) {
+	listeners, err := listenLoopback("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &authSession{state: "expected", verifier: "verifier"}
+	base := fmt.Sprintf("http://127.0.0.1:%d/", listeners[0].Addr().(*net.TCPAddr).Port)
+
+	type result struct {
+		code string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		code, err := waitForAuthCode(context.Background(), session, listeners)
+		done <- result{code, err}
+	}()
+
+	for _, query := range []string{"?code=stray&state=other", "?error=access_denied&state=other"} {
+		resp, err := http.Get(base + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET %s status = %d, want 400", query, resp.StatusCode)
+		}
+	}
+	select {
+	case r := <-done:
+		t.Fatalf("waitForAuthCode returned %q, %v after a wrong state", r.code, r.err)
+	default:
+	}
+
+	resp, err := http.Get(base + "?code=right&state=expected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if r := <-done; r.err != nil || r.code != "right" {
+		t.Errorf("waitForAuthCode = %q, %v; want right", r.code, r.err)
+	}
+}
+
+// 認証コードが届かないまま ctx が終わった場合はそのエラーを返す
+func TestWaitForAuthCodeContextDone(t *testing.T) {
+	listeners, err := listenLoopback("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err = waitForAuthCode(ctx, &authSession{state: "expected"}, listeners)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waitForAuthCode error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
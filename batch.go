@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 1回のBatchUpdateに含めるリクエスト数の上限のデフォルト
+const defaultBatchChunkSize = 500
+
+// リクエストをBatchUpdateで送信（空の場合はAPIが 400 を返すため呼び出さずに nil を返す）
+func batchUpdate(ctx context.Context, srv *sheets.Service, spreadsheetId string, requests []*sheets.Request) (*sheets.BatchUpdateSpreadsheetResponse, error) {
+	if len(requests) == 0 {
+		debugf("skipping batch update of %s: no requests", spreadsheetId)
+		return nil, nil
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}
+
+	return srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+}
+
+// リクエストをBatchUpdateで送信し、更新後のスプレッドシートをレスポンスから返す
+// 更新後に Get し直す往復を省くために使う（responseRanges と includeGridData で返すデータを指定）
+func batchUpdateWithSpreadsheet(ctx context.Context, srv *sheets.Service, spreadsheetId string, requests []*sheets.Request, responseRanges []string, includeGridData bool) (*sheets.Spreadsheet, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("at least one request is required")
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests:                     requests,
+		IncludeSpreadsheetInResponse: true,
+		ResponseRanges:               responseRanges,
+		ResponseIncludeGridData:      includeGridData,
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.UpdatedSpreadsheet == nil {
+		return nil, fmt.Errorf("batch update of %s did not include the updated spreadsheet", spreadsheetId)
+	}
+
+	return resp.UpdatedSpreadsheet, nil
+}
+
+// 行・列・シートの位置を変えるため、後続のリクエストのインデックスに影響するリクエストかどうか
+func isIndexDependent(req *sheets.Request) bool {
+	return req.InsertDimension != nil || req.DeleteDimension != nil || req.MoveDimension != nil ||
+		req.AppendDimension != nil || req.InsertRange != nil || req.DeleteRange != nil ||
+		req.AddSheet != nil || req.DeleteSheet != nil || req.DuplicateSheet != nil
+}
+
+// 2回適用すると結果が変わるリクエストかどうか（行・列やシートの追加・削除、Add* などによる追加、インデックス指定の削除）
+func isNonIdempotent(req *sheets.Request) bool {
+	return isIndexDependent(req) ||
+		req.AddBanding != nil || req.AddChart != nil || req.AddConditionalFormatRule != nil ||
+		req.AddDimensionGroup != nil || req.AddFilterView != nil || req.AddNamedRange != nil ||
+		req.AddProtectedRange != nil || req.AddSlicer != nil || req.AppendCells != nil ||
+		req.CreateDeveloperMetadata != nil || req.DuplicateFilterView != nil ||
+		req.DeleteConditionalFormatRule != nil || req.DeleteDimensionGroup != nil
+}
+
+// BatchUpdate をリトライするエラーの判定を返す
+// 5xx はサーバーで適用済みの後にレスポンスだけ失われた可能性があり、再送すると行の挿入やシートの追加が
+// 2回実行されるため、2回適用すると結果が変わるリクエストを含む場合は実行されていない 429 だけをリトライする
+func batchRetryCondition(requests []*sheets.Request) func(error) bool {
+	for _, req := range requests {
+		if isNonIdempotent(req) {
+			return isRateLimited
+		}
+	}
+	return isRetryable
+}
+
+// 大量のリクエストを chunkSize 件ずつに分割し、順番にBatchUpdateを実行して返信をまとめて返す
+// 各チャンクは一時的なエラーをリトライする（batchRetryCondition を参照）。チャンク間はアトミックではないため、
+// 途中で失敗した場合はそれまでのチャンクが適用済みのまま返る
+func batchUpdateChunked(ctx context.Context, srv *sheets.Service, spreadsheetId string, requests []*sheets.Request, chunkSize int) ([]*sheets.Response, error) {
+	if chunkSize <= 0 {
+		return nil, fmt.Errorf("chunkSize must be positive, got %d", chunkSize)
+	}
+	if len(requests) == 0 {
+		debugf("skipping batch update of %s: no requests", spreadsheetId)
+		return nil, nil
+	}
+
+	if len(requests) > chunkSize {
+		for _, req := range requests {
+			if isIndexDependent(req) {
+				log.Printf("warning: %d requests are split into chunks of %d and include row/column/sheet index changes; "+
+					"chunks are applied in order but not atomically", len(requests), chunkSize)
+				break
+			}
+		}
+	}
+
+	var replies []*sheets.Response
+	for start := 0; start < len(requests); start += chunkSize {
+		end := start + chunkSize
+		if end > len(requests) {
+			end = len(requests)
+		}
+
+		batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: requests[start:end],
+		}
+
+		var resp *sheets.BatchUpdateSpreadsheetResponse
+		err := defaultRetryConfig.doWhen(ctx, batchRetryCondition(batchUpdateRequest.Requests), func() error {
+			var err error
+			resp, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return replies, fmt.Errorf("batch update of requests %d-%d failed: %w", start, end-1, err)
+		}
+		replies = append(replies, resp.Replies...)
+	}
+
+	return replies, nil
+}
+
+// 書式設定などのリクエストをためておき、まとめてBatchUpdateで送信するためのアキュムレーター
+// Add と Commit は複数のゴルーチンから呼び出せる
+type RequestBatch struct {
+	mu            sync.Mutex
+	srv           *sheets.Service
+	spreadsheetId string
+	requests      []*sheets.Request
+}
+
+func newRequestBatch(srv *sheets.Service, spreadsheetId string) *RequestBatch {
+	return &RequestBatch{srv: srv, spreadsheetId: spreadsheetId}
+}
+
+// リクエストを追加（送信は Commit まで行わない）
+func (b *RequestBatch) Add(requests ...*sheets.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests = append(b.requests, requests...)
+}
+
+// 未送信のリクエスト数
+func (b *RequestBatch) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.requests)
+}
+
+// ためたリクエストを送信し、返信を返す（失敗した場合は未送信のリクエストが残る）
+func (b *RequestBatch) Commit(ctx context.Context) ([]*sheets.Response, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.requests) == 0 {
+		debugf("skipping commit of %s: no pending requests", b.spreadsheetId)
+		return nil, nil
+	}
+
+	replies, err := batchUpdateChunked(ctx, b.srv, b.spreadsheetId, b.requests, defaultBatchChunkSize)
+	// 送信済みのチャンクは取り除き、失敗した分以降だけを残す
+	b.requests = b.requests[len(replies):]
+	return replies, err
+}
+
+// 中断時にためたリクエストを送信する時間の上限
+const interruptFlushTimeout = 10 * time.Second
+
+// SIGINT / SIGTERM を受け取ったらキャンセルされるコンテキストを作成
+// 2回目のシグナルでは待たずに終了できるよう、キャンセル後はシグナルの監視を解除する
+func interruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// ctx が中断（キャンセル）されたら、timeout 以内でためたリクエストの送信を試みる
+// 送信できなかった場合は未送信の件数をログに出す。プロセスは終了せず、終了処理は呼び出し側に任せる
+// 返り値の関数で監視を解除する（送信中の場合は終わるまで待つ）
+func flushOnInterrupt(ctx context.Context, batch *RequestBatch, timeout time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		// 監視の解除とキャンセルが同時に起きた場合も、キャンセルされていれば送信する
+		if !errors.Is(ctx.Err(), context.Canceled) || batch.Pending() == 0 {
+			return
+		}
+		log.Printf("Interrupted; committing %d pending request(s) before exiting", batch.Pending())
+
+		flushCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if _, err := batch.Commit(flushCtx); err != nil {
+			log.Printf("Unable to commit pending requests; %d request(s) were not applied: %v", batch.Pending(), err)
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// BatchUpdate の返信から i 番目（リクエストと同じ順）を取り出す
+func replyAt(replies []*sheets.Response, i int) (*sheets.Response, error) {
+	if i < 0 || i >= len(replies) || replies[i] == nil {
+		return nil, fmt.Errorf("batch update reply %d is missing: got %d replies", i, len(replies))
+	}
+	return replies[i], nil
+}
+
+// AddSheet の返信から作成されたシートのIDを取り出す
+func addedSheetId(replies []*sheets.Response, i int) (int64, error) {
+	reply, err := replyAt(replies, i)
+	if err != nil {
+		return 0, err
+	}
+	if reply.AddSheet == nil || reply.AddSheet.Properties == nil {
+		return 0, fmt.Errorf("batch update reply %d is not an AddSheet reply", i)
+	}
+	return reply.AddSheet.Properties.SheetId, nil
+}
+
+// DuplicateSheet の返信から複製されたシートのIDを取り出す
+func duplicatedSheetId(replies []*sheets.Response, i int) (int64, error) {
+	reply, err := replyAt(replies, i)
+	if err != nil {
+		return 0, err
+	}
+	if reply.DuplicateSheet == nil || reply.DuplicateSheet.Properties == nil {
+		return 0, fmt.Errorf("batch update reply %d is not a DuplicateSheet reply", i)
+	}
+	return reply.DuplicateSheet.Properties.SheetId, nil
+}
+
+// AddProtectedRange の返信から保護範囲のIDを取り出す
+func addedProtectedRangeId(replies []*sheets.Response, i int) (int64, error) {
+	reply, err := replyAt(replies, i)
+	if err != nil {
+		return 0, err
+	}
+	if reply.AddProtectedRange == nil || reply.AddProtectedRange.ProtectedRange == nil {
+		return 0, fmt.Errorf("batch update reply %d is not an AddProtectedRange reply", i)
+	}
+	return reply.AddProtectedRange.ProtectedRange.ProtectedRangeId, nil
+}
+
+// AddFilterView の返信からフィルタ表示のIDを取り出す
+func addedFilterViewId(replies []*sheets.Response, i int) (int64, error) {
+	reply, err := replyAt(replies, i)
+	if err != nil {
+		return 0, err
+	}
+	if reply.AddFilterView == nil || reply.AddFilterView.Filter == nil {
+		return 0, fmt.Errorf("batch update reply %d is not an AddFilterView reply", i)
+	}
+	return reply.AddFilterView.Filter.FilterViewId, nil
+}
+
+// AddBanding の返信からバンドのIDを取り出す
+func addedBandedRangeId(replies []*sheets.Response, i int) (int64, error) {
+	reply, err := replyAt(replies, i)
+	if err != nil {
+		return 0, err
+	}
+	if reply.AddBanding == nil || reply.AddBanding.BandedRange == nil {
+		return 0, fmt.Errorf("batch update reply %d is not an AddBanding reply", i)
+	}
+	return reply.AddBanding.BandedRange.BandedRangeId, nil
+}
+
+// AddChart の返信からグラフのIDを取り出す
+func addedChartId(replies []*sheets.Response, i int) (int64, error) {
+	reply, err := replyAt(replies, i)
+	if err != nil {
+		return 0, err
+	}
+	if reply.AddChart == nil || reply.AddChart.Chart == nil {
+		return 0, fmt.Errorf("batch update reply %d is not an AddChart reply", i)
+	}
+	return reply.AddChart.Chart.ChartId, nil
+}
+
+// AddNamedRange の返信から名前付き範囲のIDを取り出す
+func addedNamedRangeId(replies []*sheets.Response, i int) (string, error) {
+	reply, err := replyAt(replies, i)
+	if err != nil {
+		return "", err
+	}
+	if reply.AddNamedRange == nil || reply.AddNamedRange.NamedRange == nil {
+		return "", fmt.Errorf("batch update reply %d is not an AddNamedRange reply", i)
+	}
+	return reply.AddNamedRange.NamedRange.NamedRangeId, nil
+}
+
+// FindReplace の返信から置換した件数を取り出す
+func replacedOccurrences(replies []*sheets.Response, i int) (int64, error) {
+	reply, err := replyAt(replies, i)
+	if err != nil {
+		return 0, err
+	}
+	if reply.FindReplace == nil {
+		return 0, fmt.Errorf("batch update reply %d is not a FindReplace reply", i)
+	}
+	return reply.FindReplace.OccurrencesChanged, nil
+}
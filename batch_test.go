@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func yearMonthTestRequests(t *testing.T) []*sheets.Request {
+	t.Helper()
+	requests, err := yearMonthRequests(&sheets.Spreadsheet{Sheets: []*sheets.Sheet{{Properties: &sheets.SheetProperties{SheetId: 1}}}}, 2024, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return requests
+}
+
+// 中断されたら、ためたリクエストを送信してから監視を終える
+func TestFlushOnInterruptCommitsPending(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	batch := newRequestBatch(fake.service(t), "fake")
+	batch.Add(yearMonthTestRequests(t)...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := flushOnInterrupt(ctx, batch, interruptFlushTimeout)
+	cancel()
+	stop()
+
+	if batch.Pending() != 0 {
+		t.Errorf("%d requests are still pending after the interrupt", batch.Pending())
+	}
+	if len(fake.requests) != 2 {
+		t.Errorf("server received %d requests, want 2", len(fake.requests))
+	}
+}
+
+// 中断されずに監視を解除した場合は何も送信しない
+func TestFlushOnInterruptStopWithoutInterrupt(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	batch := newRequestBatch(fake.service(t), "fake")
+	batch.Add(yearMonthTestRequests(t)...)
+
+	stop := flushOnInterrupt(context.Background(), batch, interruptFlushTimeout)
+	stop()
+
+	if batch.Pending() != 2 || len(fake.requests) != 0 {
+		t.Errorf("pending = %d, sent = %d; want the requests left for the caller", batch.Pending(), len(fake.requests))
+	}
+}
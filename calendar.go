@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// 月の日数と1日の曜日を返す
+func monthInfo(year int, month int) (days int, firstWeekday time.Weekday, err error) {
+	if month < 1 || month > 12 {
+		return 0, 0, fmt.Errorf("month %d is out of range 1-12", month)
+	}
+
+	first := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	// 翌月の0日 = 当月の末日
+	last := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC)
+
+	return last.Day(), first.Weekday(), nil
+}
+
+// スプレッドシートのシリアル値の起点（1899年12月30日）
+var serialEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// シリアル値（起点からの日数、小数部は時刻）を loc の日時に変換
+// シリアル値はタイムゾーンを持たない壁時計の日時なので、夏時間の切り替えがあっても表示どおりの時刻になる
+func serialToTime(serial float64, loc *time.Location) time.Time {
+	days := math.Floor(serial)
+	// 浮動小数点の誤差で 10:59:59.999 のようにならないようミリ秒に丸める
+	ms := int64(math.Round((serial - days) * 24 * 60 * 60 * 1000))
+	clock := time.Duration(ms) * time.Millisecond
+	h, m, sec := int(clock/time.Hour), int(clock%time.Hour/time.Minute), int(clock%time.Minute/time.Second)
+	return time.Date(1899, 12, 30+int(days), h, m, sec, int(clock%time.Second), loc)
+}
+
+// 日時をそのタイムゾーンでの壁時計の日時としてシリアル値に変換
+func timeToSerial(t time.Time) float64 {
+	// time.Duration は約292年で飽和するため、日数は Unix 秒の差から求める
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	days := float64((date.Unix() - serialEpoch.Unix()) / (24 * 60 * 60))
+	clock := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	return days + clock.Hours()/24
+}
+
+// 月を日曜始まりの週に分け、各週の初日と末日（日にち）を返す
+// 月初と月末の週は月の範囲で切るため、7日に満たない場合がある
+func monthWeeks(year int, month int) ([][2]int, error) {
+	days, firstWeekday, err := monthInfo(year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	var weeks [][2]int
+	start := 1
+	for day := 1; day <= days; day++ {
+		if (int(firstWeekday)+day-1)%7 == int(time.Saturday) || day == days {
+			weeks = append(weeks, [2]int{start, day})
+			start = day + 1
+		}
+	}
+	return weeks, nil
+}
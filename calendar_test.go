@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestTimeToSerial(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want float64
+	}{
+		{t: time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC), want: 0},
+		{t: time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC), want: 61},
+		{t: time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC), want: 45292.75},
+		// time.Duration では表せない起点からの差
+		{t: time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC), want: 146099},
+		{t: time.Date(9999, 12, 31, 12, 0, 0, 0, time.UTC), want: 2958465.5},
+	}
+	for _, tt := range tests {
+		if got := timeToSerial(tt.t); got != tt.want {
+			t.Errorf("timeToSerial(%v) = %v, want %v", tt.t, got, tt.want)
+		}
+	}
+}
+
+// 夏時間の切り替え前後でも壁時計の日時のまま往復する
+func TestSerialRoundTripAcrossDST(t *testing.T) {
+	tests := []struct {
+		zone  string
+		times [][6]int
+	}{
+		{
+			// 2024-03-10 02:00 に1時間進み、2024-11-03 02:00 に1時間戻る
+			zone: "America/New_York",
+			times: [][6]int{
+				{2024, 3, 10, 1, 59, 59},
+				{2024, 3, 10, 3, 0, 0},
+				{2024, 3, 10, 23, 30, 0},
+				{2024, 11, 3, 0, 59, 59},
+				{2024, 11, 3, 1, 30, 0},
+				{2024, 11, 3, 2, 0, 0},
+			},
+		},
+		{
+			// 2024-03-31 02:00 に1時間進み、2024-10-27 03:00 に1時間戻る
+			zone: "Europe/Berlin",
+			times: [][6]int{
+				{2024, 3, 31, 1, 59, 59},
+				{2024, 3, 31, 3, 0, 0},
+				{2024, 3, 31, 12, 0, 0},
+				{2024, 10, 27, 1, 59, 59},
+				{2024, 10, 27, 2, 30, 0},
+				{2024, 10, 27, 3, 0, 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		loc, err := time.LoadLocation(tt.zone)
+		if err != nil {
+			t.Fatalf("LoadLocation(%q): %v", tt.zone, err)
+		}
+		for _, c := range tt.times {
+			tm := time.Date(c[0], time.Month(c[1]), c[2], c[3], c[4], c[5], 0, loc)
+			serial := timeToSerial(tm)
+			got := serialToTime(serial, loc)
+			if !got.Equal(tm) || got.Hour() != c[3] || got.Minute() != c[4] || got.Second() != c[5] {
+				t.Errorf("%s: %v -> %v -> %v", tt.zone, tm, serial, got)
+			}
+			if again := timeToSerial(got); again != serial {
+				t.Errorf("%s: serial %v -> %v -> %v", tt.zone, serial, got, again)
+			}
+		}
+	}
+}
+
+// 切り替え日の前後の日は、その日の実際の長さに関係なくシリアル値で1.0ずつ離れる
+func TestSerialDaysAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := timeToSerial(time.Date(2024, 3, 9, 9, 0, 0, 0, loc))
+	after := timeToSerial(time.Date(2024, 3, 10, 9, 0, 0, 0, loc))
+	if after-before != 1 {
+		t.Errorf("serials across the spring-forward day differ by %v, want 1", after-before)
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 数式の文字列リテラルとして使えるようにダブルクォートをエスケープ
+func formulaString(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// http/https の URL かどうかを検証
+func validateHTTPURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %v", rawURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url %q must be an absolute http or https url", rawURL)
+	}
+	return nil
+}
+
+// セルにハイパーリンクを設定（行・列は0始まり）
+func setHyperlink(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, row int64, col int64, text string, linkURL string) error {
+	if err := validateHTTPURL(linkURL); err != nil {
+		return err
+	}
+
+	props, err := getSheetPropertiesById(ctx, srv, spreadsheetId, sheetId)
+	if err != nil {
+		return err
+	}
+
+	a1 := cellA1(props.Title, row, col)
+	valueRange := &sheets.ValueRange{
+		Range:  a1,
+		Values: [][]interface{}{{"=HYPERLINK(" + formulaString(linkURL) + "," + formulaString(text) + ")"}},
+	}
+
+	_, err = srv.Spreadsheets.Values.Update(spreadsheetId, a1, valueRange).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Go の値を UpdateCells 用の ExtendedValue に変換（"=" で始まる文字列は数式として扱う）
+func toExtendedValue(v interface{}) (*sheets.ExtendedValue, error) {
+	v, err := normalizeValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return &sheets.ExtendedValue{BoolValue: &v}, nil
+	case string:
+		if strings.HasPrefix(v, "=") {
+			return &sheets.ExtendedValue{FormulaValue: &v}, nil
+		}
+		return &sheets.ExtendedValue{StringValue: &v}, nil
+	case int64:
+		n := float64(v)
+		return &sheets.ExtendedValue{NumberValue: &n}, nil
+	case float64:
+		return &sheets.ExtendedValue{NumberValue: &v}, nil
+	default:
+		return nil, fmt.Errorf("unsupported cell value type %T", v)
+	}
+}
+
+// 値と表示形式を同時に設定する UpdateCells リクエストを作成（行・列は0始まりの左上セル）
+func updateCellsWithFormatRequest(sheetId int64, row int64, col int64, values [][]interface{}, numberFormat *sheets.NumberFormat) (*sheets.Request, error) {
+	rows := make([]*sheets.RowData, 0, len(values))
+	for _, rowValues := range values {
+		cells := make([]*sheets.CellData, 0, len(rowValues))
+		for _, v := range rowValues {
+			ev, err := toExtendedValue(v)
+			if err != nil {
+				return nil, err
+			}
+			cells = append(cells, &sheets.CellData{
+				UserEnteredValue:  ev,
+				UserEnteredFormat: &sheets.CellFormat{NumberFormat: numberFormat},
+			})
+		}
+		rows = append(rows, &sheets.RowData{Values: cells})
+	}
+
+	return &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start: &sheets.GridCoordinate{
+				SheetId:     sheetId,
+				RowIndex:    row,
+				ColumnIndex: col,
+			},
+			Rows:   rows,
+			Fields: "userEnteredValue,userEnteredFormat.numberFormat",
+		},
+	}, nil
+}
+
+// 値と表示形式を1回のBatchUpdateで書き込み
+func updateCellsWithFormat(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, row int64, col int64, values [][]interface{}, numberFormat *sheets.NumberFormat) error {
+	updateCellsRequest, err := updateCellsWithFormatRequest(sheetId, row, col, values, numberFormat)
+	if err != nil {
+		return err
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{updateCellsRequest},
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 文字列をそのまま文字列として書き込む（行・列は0始まりの左上セル）
+// "09" のようなシフトのコードが数値の 9 にならないよう、表示形式も書式なしテキストにする
+// "=" で始まる文字列も数式にはしない
+func writeText(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, row int64, col int64, values [][]string) error {
+	textFormat := &sheets.CellFormat{NumberFormat: &sheets.NumberFormat{Type: "TEXT"}}
+
+	rows := make([]*sheets.RowData, 0, len(values))
+	for _, rowValues := range values {
+		cells := make([]*sheets.CellData, 0, len(rowValues))
+		for i := range rowValues {
+			cells = append(cells, &sheets.CellData{
+				UserEnteredValue:  &sheets.ExtendedValue{StringValue: &rowValues[i]},
+				UserEnteredFormat: textFormat,
+			})
+		}
+		rows = append(rows, &sheets.RowData{Values: cells})
+	}
+
+	updateCellsRequest := &sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start: &sheets.GridCoordinate{
+				SheetId:     sheetId,
+				RowIndex:    row,
+				ColumnIndex: col,
+			},
+			Rows:   rows,
+			Fields: "userEnteredValue,userEnteredFormat.numberFormat",
+		},
+	}
+
+	_, err := batchUpdate(ctx, srv, spreadsheetId, []*sheets.Request{updateCellsRequest})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 書式の異なる部分を含むテキスト（リッチテキスト）をセルに設定（行・列は0始まり）
+// runs の StartIndex は昇順で、テキストの長さ（UTF-16 単位）未満でなければならない
+func setRichText(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, row int64, col int64, text string, runs []*sheets.TextFormatRun) error {
+	textLength := int64(len(utf16.Encode([]rune(text))))
+	// 呼び出し側の runs を変更しないよう、コピーに ForceSendFields を設定する
+	sendRuns := make([]*sheets.TextFormatRun, len(runs))
+	for i, run := range runs {
+		if run == nil {
+			return fmt.Errorf("text format run %d is nil", i)
+		}
+		if run.StartIndex < 0 || run.StartIndex >= textLength {
+			return fmt.Errorf("text format run %d starts at %d, outside text of length %d", i, run.StartIndex, textLength)
+		}
+		if i > 0 && run.StartIndex <= runs[i-1].StartIndex {
+			return fmt.Errorf("text format run %d starts at %d, not after the previous run at %d", i, run.StartIndex, runs[i-1].StartIndex)
+		}
+		// 先頭（0）の開始位置も省略されないようにする
+		sendRun := *run
+		sendRun.ForceSendFields = append(append([]string{}, run.ForceSendFields...), "StartIndex")
+		sendRuns[i] = &sendRun
+	}
+
+	updateCellsRequest := sheets.Request{
+		UpdateCells: &sheets.UpdateCellsRequest{
+			Start: &sheets.GridCoordinate{
+				SheetId:     sheetId,
+				RowIndex:    row,
+				ColumnIndex: col,
+			},
+			Rows: []*sheets.RowData{
+				{
+					Values: []*sheets.CellData{
+						{
+							UserEnteredValue: &sheets.ExtendedValue{StringValue: &text},
+							TextFormatRuns:   sendRuns,
+						},
+					},
+				},
+			},
+			Fields: "userEnteredValue,textFormatRuns",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&updateCellsRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 書き込む値の数値型をそろえる
+// int8〜uint64 や独自の数値型は int64 に、float32 は10進表記を保ったまま float64 に、
+// json.Number は数値に変換し、整数値の浮動小数点数は指数表記にならないよう int64 にする
+func normalizeValue(v interface{}) (interface{}, error) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid json.Number %q: %v", n, err)
+		}
+		return normalizeFloat(f)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return normalizeFloat(float64(u))
+		}
+		return int64(u), nil
+	case reflect.Float32:
+		// float32 のまま float64 にすると 0.1 が 0.10000000149011612 になるため10進表記を経由
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(rv.Float(), 'g', -1, 32), 64)
+		return normalizeFloat(f)
+	case reflect.Float64:
+		return normalizeFloat(rv.Float())
+	}
+	return v, nil
+}
+
+func normalizeFloat(f float64) (interface{}, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("cannot write non-finite number %v", f)
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f), nil
+	}
+	return f, nil
+}
+
+// 2次元の値すべてに normalizeValue を適用したコピーを返す
+func normalizeValues(values [][]interface{}) ([][]interface{}, error) {
+	normalized := make([][]interface{}, len(values))
+	for r, row := range values {
+		normalized[r] = make([]interface{}, len(row))
+		for c, v := range row {
+			n, err := normalizeValue(v)
+			if err != nil {
+				return nil, fmt.Errorf("row %d, column %d: %w", r+1, c+1, err)
+			}
+			normalized[r][c] = n
+		}
+	}
+	return normalized, nil
+}
+
+// 時間の長さをスプレッドシートのシリアル値（1日 = 1.0）に変換
+func durationToSerial(d time.Duration) float64 {
+	return d.Hours() / 24
+}
+
+// 時間の長さをシリアル値として範囲に書き込み
+// 表示には setElapsedTimeFormat で "[h]:mm" の形式を設定しておく
+func writeDurations(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string, durations [][]time.Duration) (UpdateResult, error) {
+	values := make([][]interface{}, len(durations))
+	for r, row := range durations {
+		values[r] = make([]interface{}, len(row))
+		for c, d := range row {
+			values[r][c] = durationToSerial(d)
+		}
+	}
+
+	return writeRange(ctx, srv, spreadsheetId, a1, values)
+}
+
+// =IMAGE の表示方法
+// Mode は 1: セルに合わせて縦横比を維持、2: セルに合わせて引き伸ばし、3: 元のサイズ、4: Height・Width（ピクセル）で指定
+type imageOptions struct {
+	Mode     int
+	Height   int
+	Width    int
+	OverCell bool
+}
+
+// セルに =IMAGE(url) で画像を表示（行・列は0始まり）
+// OverCell（セルの上に浮かぶ画像）は Sheets API に挿入用のリクエストがないためエラーを返す
+func setCellImage(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, row int64, col int64, imageURL string, opts imageOptions) error {
+	if err := validateHTTPURL(imageURL); err != nil {
+		return err
+	}
+	if opts.OverCell {
+		return fmt.Errorf("over-cell images cannot be inserted through the Sheets API; use an in-cell =IMAGE instead")
+	}
+
+	formula := "=IMAGE(" + formulaString(imageURL)
+	switch opts.Mode {
+	case 0, 1:
+	case 2, 3:
+		formula += "," + strconv.Itoa(opts.Mode)
+	case 4:
+		if opts.Height <= 0 || opts.Width <= 0 {
+			return fmt.Errorf("image mode 4 requires a positive height and width")
+		}
+		formula += fmt.Sprintf(",4,%d,%d", opts.Height, opts.Width)
+	default:
+		return fmt.Errorf("invalid image mode %d: must be 1 to 4", opts.Mode)
+	}
+	formula += ")"
+
+	props, err := getSheetPropertiesById(ctx, srv, spreadsheetId, sheetId)
+	if err != nil {
+		return err
+	}
+
+	a1 := cellA1(props.Title, row, col)
+	valueRange := &sheets.ValueRange{
+		Range:  a1,
+		Values: [][]interface{}{{formula}},
+	}
+
+	_, err = srv.Spreadsheets.Values.Update(spreadsheetId, a1, valueRange).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 範囲のセルをチェックボックスにする（BOOLEAN の入力規則）
+// initialize が true の場合は、すべてのセルを未チェック（FALSE）にする
+func setCheckboxes(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange, initialize bool) error {
+	// 終端が始端以前の空の範囲もここでエラーになる
+	if err := validateGridRange(gridRange); err != nil {
+		return err
+	}
+
+	rule := &sheets.DataValidationRule{
+		Condition: &sheets.BooleanCondition{Type: "BOOLEAN"},
+		Strict:    true,
+	}
+
+	var request *sheets.Request
+	if initialize {
+		request = &sheets.Request{
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: gridRange,
+				Cell: &sheets.CellData{
+					DataValidation:   rule,
+					UserEnteredValue: &sheets.ExtendedValue{BoolValue: new(bool)},
+				},
+				Fields: "dataValidation,userEnteredValue",
+			},
+		}
+	} else {
+		request = &sheets.Request{
+			SetDataValidation: &sheets.SetDataValidationRequest{
+				Range: gridRange,
+				Rule:  rule,
+			},
+		}
+	}
+
+	_, err := batchUpdate(ctx, srv, spreadsheetId, []*sheets.Request{request})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
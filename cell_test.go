@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestNormalizeValue(t *testing.T) {
+	type hours int16
+
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "int", v: int(-7), want: int64(-7)},
+		{name: "int8", v: int8(-8), want: int64(-8)},
+		{name: "int16", v: int16(16), want: int64(16)},
+		{name: "int32", v: int32(-32), want: int64(-32)},
+		{name: "int64", v: int64(math.MinInt64), want: int64(math.MinInt64)},
+		{name: "uint", v: uint(7), want: int64(7)},
+		{name: "uint8", v: uint8(255), want: int64(255)},
+		{name: "uint16", v: uint16(16), want: int64(16)},
+		{name: "uint32", v: uint32(math.MaxUint32), want: int64(math.MaxUint32)},
+		{name: "uint64", v: uint64(math.MaxInt64), want: int64(math.MaxInt64)},
+		{name: "uint64 above MaxInt64", v: uint64(math.MaxUint64), want: float64(math.MaxUint64)},
+		{name: "named integer type", v: hours(8), want: int64(8)},
+		{name: "float32 0.1", v: float32(0.1), want: 0.1},
+		{name: "float32 integral", v: float32(3), want: int64(3)},
+		{name: "float64", v: 7.25, want: 7.25},
+		{name: "integral float64", v: 8.0, want: int64(8)},
+		{name: "negative integral float64", v: -2.0, want: int64(-2)},
+		{name: "float64 beyond 2^53", v: 1e20, want: 1e20},
+		{name: "json.Number int", v: json.Number("42"), want: int64(42)},
+		{name: "json.Number float", v: json.Number("7.5"), want: 7.5},
+		{name: "json.Number integral float", v: json.Number("8.0"), want: int64(8)},
+		{name: "json.Number invalid", v: json.Number("eight"), wantErr: true},
+		{name: "NaN", v: math.NaN(), wantErr: true},
+		{name: "+Inf", v: math.Inf(1), wantErr: true},
+		{name: "-Inf float32", v: float32(math.Inf(-1)), wantErr: true},
+		{name: "string", v: "09", want: "09"},
+		{name: "bool", v: true, want: true},
+		{name: "nil", v: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeValue(tt.v)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("normalizeValue(%#v) = %#v, want error", tt.v, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeValue(%#v): %v", tt.v, err)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeValue(%#v) = %#v (%T), want %#v (%T)", tt.v, got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeValuesReportsCell(t *testing.T) {
+	_, err := normalizeValues([][]interface{}{{1, 2}, {3, math.NaN()}})
+	if err == nil || err.Error() != "row 2, column 2: cannot write non-finite number NaN" {
+		t.Errorf("normalizeValues error = %v", err)
+	}
+}
+
+// 同じ runs で繰り返し呼び出しても、呼び出し側の ForceSendFields は増えない
+func TestSetRichTextKeepsCallerRuns(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+
+	runs := []*sheets.TextFormatRun{
+		{StartIndex: 0, Format: &sheets.TextFormat{Bold: true}},
+		{StartIndex: 2},
+	}
+	for i := 0; i < 2; i++ {
+		if err := setRichText(context.Background(), srv, "fake", 1, 0, 0, "山田太郎", runs); err != nil {
+			t.Fatalf("setRichText: %v", err)
+		}
+	}
+
+	for i, run := range runs {
+		if len(run.ForceSendFields) != 0 {
+			t.Errorf("run %d ForceSendFields = %q, want none", i, run.ForceSendFields)
+		}
+	}
+	for _, request := range fake.requests {
+		sent := request.UpdateCells.Rows[0].Values[0].TextFormatRuns
+		if len(sent) != 2 || sent[0].StartIndex != 0 || sent[1].StartIndex != 2 {
+			t.Errorf("sent runs = %+v", sent)
+		}
+	}
+}
+
+// runs に nil が含まれる場合はリクエストを送らずにエラーを返す
+func TestSetRichTextRejectsNilRun(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+
+	runs := []*sheets.TextFormatRun{{StartIndex: 0}, nil}
+	err := setRichText(context.Background(), srv, "fake", 1, 0, 0, "山田太郎", runs)
+	if err == nil || err.Error() != "text format run 1 is nil" {
+		t.Errorf("setRichText error = %v, want text format run 1 is nil", err)
+	}
+	if fake.calls != 0 {
+		t.Errorf("made %d API calls, want 0", fake.calls)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 縦棒グラフを追加し、作成されたグラフのIDを返す
+// domainRange は横軸（社員名など）、seriesRange は値（合計勤務時間など）の範囲
+func addColumnChart(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, domainRange *sheets.GridRange, seriesRange *sheets.GridRange, title string) (int64, error) {
+	if domainRange == nil || seriesRange == nil {
+		return 0, fmt.Errorf("domain and series ranges are required")
+	}
+	// 範囲のシートIDは未設定（0）か sheetId と同じでなければならない
+	domain, err := chartRangeOnSheet("domain", domainRange, sheetId)
+	if err != nil {
+		return 0, err
+	}
+	series, err := chartRangeOnSheet("series", seriesRange, sheetId)
+	if err != nil {
+		return 0, err
+	}
+	if domain.StartRowIndex != series.StartRowIndex || domain.EndRowIndex != series.EndRowIndex {
+		return 0, fmt.Errorf("domain rows %d-%d and series rows %d-%d must match",
+			domain.StartRowIndex, domain.EndRowIndex, series.StartRowIndex, series.EndRowIndex)
+	}
+	if series.EndColumnIndex == 0 {
+		return 0, fmt.Errorf("series range needs an end column to place the chart beside it")
+	}
+
+	addChartRequest := sheets.Request{
+		AddChart: &sheets.AddChartRequest{
+			Chart: &sheets.EmbeddedChart{
+				Spec: &sheets.ChartSpec{
+					Title: title,
+					BasicChart: &sheets.BasicChartSpec{
+						ChartType:      "COLUMN",
+						LegendPosition: "NO_LEGEND",
+						HeaderCount:    1,
+						Domains: []*sheets.BasicChartDomain{
+							{
+								Domain: &sheets.ChartData{
+									SourceRange: &sheets.ChartSourceRange{Sources: []*sheets.GridRange{domain}},
+								},
+							},
+						},
+						Series: []*sheets.BasicChartSeries{
+							{
+								Series: &sheets.ChartData{
+									SourceRange: &sheets.ChartSourceRange{Sources: []*sheets.GridRange{series}},
+								},
+								TargetAxis: "LEFT_AXIS",
+							},
+						},
+					},
+				},
+				// データの右隣に配置（EndColumnIndex は範囲に含まれない最初の列）
+				Position: &sheets.EmbeddedObjectPosition{
+					OverlayPosition: &sheets.OverlayPosition{
+						AnchorCell: &sheets.GridCoordinate{
+							SheetId:     sheetId,
+							RowIndex:    series.StartRowIndex,
+							ColumnIndex: series.EndColumnIndex,
+						},
+						WidthPixels:  600,
+						HeightPixels: 371,
+					},
+				},
+			},
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&addChartRequest},
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	return addedChartId(resp.Replies, 0)
+}
+
+// グラフの範囲を検証し、sheetId を設定したコピーを返す
+func chartRangeOnSheet(name string, gridRange *sheets.GridRange, sheetId int64) (*sheets.GridRange, error) {
+	if gridRange.SheetId != 0 && gridRange.SheetId != sheetId {
+		return nil, fmt.Errorf("%s range is on sheet %d, not on sheet %d", name, gridRange.SheetId, sheetId)
+	}
+	gr, err := onSheet(gridRange, sheetId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s range: %w", name, err)
+	}
+	return gr, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// グラフは値の範囲のすぐ右の列に配置する
+func TestAddColumnChartAnchor(t *testing.T) {
+	fake := newFakeSheets("集計", 20, 10)
+	srv := fake.service(t)
+
+	domain := &sheets.GridRange{StartRowIndex: 0, EndRowIndex: 6, StartColumnIndex: 0, EndColumnIndex: 1}
+	series := &sheets.GridRange{StartRowIndex: 0, EndRowIndex: 6, StartColumnIndex: 1, EndColumnIndex: 2}
+	if _, err := addColumnChart(context.Background(), srv, "fake", 1, domain, series, "勤務時間"); err != nil {
+		t.Fatalf("addColumnChart: %v", err)
+	}
+
+	chart := fake.requests[0].AddChart.Chart
+	anchor := chart.Position.OverlayPosition.AnchorCell
+	if anchor.ColumnIndex != 2 || anchor.RowIndex != 0 {
+		t.Errorf("anchor = row %d, column %d; want row 0, column 2 (C)", anchor.RowIndex, anchor.ColumnIndex)
+	}
+	if got := chart.Spec.BasicChart.Domains[0].Domain.SourceRange.Sources[0].SheetId; got != 1 {
+		t.Errorf("domain sheet ID = %d, want 1", got)
+	}
+	if domain.SheetId != 0 || series.SheetId != 0 {
+		t.Error("caller's ranges were changed")
+	}
+}
+
+func TestAddColumnChartValidatesRanges(t *testing.T) {
+	fake := newFakeSheets("集計", 20, 10)
+	srv := fake.service(t)
+
+	valid := func() *sheets.GridRange {
+		return &sheets.GridRange{StartRowIndex: 0, EndRowIndex: 6, StartColumnIndex: 1, EndColumnIndex: 2}
+	}
+	tests := []struct {
+		name   string
+		domain *sheets.GridRange
+		series *sheets.GridRange
+	}{
+		{name: "nil domain", domain: nil, series: valid()},
+		{name: "nil series", domain: valid(), series: nil},
+		{name: "other sheet", domain: &sheets.GridRange{SheetId: 2, EndRowIndex: 6, EndColumnIndex: 1}, series: valid()},
+		{name: "inverted rows", domain: valid(), series: &sheets.GridRange{StartRowIndex: 6, EndRowIndex: 2, StartColumnIndex: 1, EndColumnIndex: 2}},
+		{name: "different row spans", domain: &sheets.GridRange{StartRowIndex: 0, EndRowIndex: 5, EndColumnIndex: 1}, series: valid()},
+		{name: "open-ended series columns", domain: valid(), series: &sheets.GridRange{StartRowIndex: 0, EndRowIndex: 6, StartColumnIndex: 1}},
+	}
+	for _, tt := range tests {
+		if _, err := addColumnChart(context.Background(), srv, "fake", 1, tt.domain, tt.series, "勤務時間"); err == nil {
+			t.Errorf("%s: addColumnChart succeeded", tt.name)
+		}
+	}
+	if len(fake.requests) != 0 {
+		t.Errorf("sent %d requests for invalid ranges", len(fake.requests))
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// 一定間隔以上あけてリクエストを送るための簡易レートリミッター
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// 1分あたりのリクエスト数からレートリミッターを作成（0以下なら無制限）
+func newRateLimiter(requestsPerMinute int) *rateLimiter {
+	l := &rateLimiter{}
+	if requestsPerMinute > 0 {
+		l.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return l
+}
+
+// 次のリクエストを送ってよい時刻まで待機
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// 認証済みの Service をゴルーチン間で共有するためのクライアント
+//
+// *sheets.Service とその http.Client は並行利用が可能で、レートリミッターは内部でロックを取り、
+// リトライ設定と値の入力方法は作成後に変更しないため、Client のメソッドは複数のゴルーチンから同時に呼び出せる。
+// 同じスプレッドシートへの書き込みの順序は保証しないので、順序が必要な場合は呼び出し側で直列化すること。
+type Client struct {
+	srv     *sheets.Service
+	limiter *rateLimiter
+	retry   retryConfig
+	// UpdateValues で inputOption を省略した場合の入力方法（作成時の -value-input の設定）
+	valueInputOption string
+}
+
+// 認証済みの http.Client から Service を作成
+// 認証とは切り離してあるため、テストでは記録・再生用の http.Client を渡せる
+func newSheetsService(ctx context.Context, httpClient *http.Client) (*sheets.Service, error) {
+	if httpClient == nil {
+		return nil, fmt.Errorf("http client is required")
+	}
+	return sheets.NewService(ctx, option.WithHTTPClient(httpClient))
+}
+
+// 認証済みの http.Client から Client を作成
+func newClientFromHTTPClient(ctx context.Context, httpClient *http.Client, retry retryConfig, requestsPerMinute int) (*Client, error) {
+	srv, err := newSheetsService(ctx, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(srv, retry, requestsPerMinute), nil
+}
+
+// Service をラップした Client を作成
+func newClient(srv *sheets.Service, retry retryConfig, requestsPerMinute int) *Client {
+	return &Client{
+		srv:              srv,
+		limiter:          newRateLimiter(requestsPerMinute),
+		retry:            retry,
+		valueInputOption: valueInputOption,
+	}
+}
+
+// ラップしている Service を返す（レート制限とリトライは適用されない）
+func (c *Client) Service() *sheets.Service {
+	return c.srv
+}
+
+// レート制限をかけつつ、一時的なエラーをリトライして fn を実行
+func (c *Client) call(ctx context.Context, fn func() error) error {
+	return c.callWhen(ctx, isRetryable, fn)
+}
+
+// レート制限をかけつつ、shouldRetry が true を返すエラーの間だけリトライして fn を実行
+func (c *Client) callWhen(ctx context.Context, shouldRetry func(error) bool, fn func() error) error {
+	return c.retry.doWhen(ctx, shouldRetry, func() error {
+		if err := c.limiter.wait(ctx); err != nil {
+			return err
+		}
+		return fn()
+	})
+}
+
+// スプレッドシートを取得
+func (c *Client) Get(ctx context.Context, spreadsheetId string) (*sheets.Spreadsheet, error) {
+	var spreadsheet *sheets.Spreadsheet
+	err := c.call(ctx, func() error {
+		var err error
+		spreadsheet, err = c.srv.Spreadsheets.Get(spreadsheetId).Context(ctx).Do()
+		return err
+	})
+	return spreadsheet, err
+}
+
+// BatchUpdate を実行（2回適用すると結果が変わるリクエストを含む場合は 5xx をリトライしない）
+func (c *Client) BatchUpdate(ctx context.Context, spreadsheetId string, req *sheets.BatchUpdateSpreadsheetRequest) (*sheets.BatchUpdateSpreadsheetResponse, error) {
+	var resp *sheets.BatchUpdateSpreadsheetResponse
+	err := c.callWhen(ctx, batchRetryCondition(req.Requests), func() error {
+		var err error
+		resp, err = c.srv.Spreadsheets.BatchUpdate(spreadsheetId, req).Context(ctx).Do()
+		return err
+	})
+	return resp, err
+}
+
+// 範囲の値を取得
+func (c *Client) GetValues(ctx context.Context, spreadsheetId string, a1 string) (*sheets.ValueRange, error) {
+	var valueRange *sheets.ValueRange
+	err := c.call(ctx, func() error {
+		var err error
+		valueRange, err = c.srv.Spreadsheets.Values.Get(spreadsheetId, a1).Context(ctx).Do()
+		return err
+	})
+	return valueRange, err
+}
+
+// 範囲に値を書き込み、書き込んだサイズを返す（inputOption が空の場合は Client の作成時の -value-input の設定を使う）
+func (c *Client) UpdateValues(ctx context.Context, spreadsheetId string, a1 string, values [][]interface{}, inputOption string) (UpdateResult, error) {
+	if inputOption == "" {
+		inputOption = c.valueInputOption
+	}
+	values, err := normalizeValues(values)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	valueRange := &sheets.ValueRange{
+		Range:          a1,
+		Values:         values,
+		MajorDimension: "ROWS",
+	}
+
+	var resp *sheets.UpdateValuesResponse
+	err = c.call(ctx, func() error {
+		var err error
+		resp, err = c.srv.Spreadsheets.Values.Update(spreadsheetId, a1, valueRange).ValueInputOption(inputOption).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	return updateResultFrom(resp), nil
+}
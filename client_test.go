@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// 常に 503 を返すサーバーに対して BatchUpdate を送り、送信回数を返す
+func countBatchUpdateAttempts(t *testing.T, requests []*sheets.Request) int32 {
+	t.Helper()
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		http.Error(w, `{"error":{"code":503,"message":"backend error"}}`, http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	srv, err := sheets.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(srv, retryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}, 0)
+	if _, err := client.BatchUpdate(context.Background(), "fake", &sheets.BatchUpdateSpreadsheetRequest{Requests: requests}); err == nil {
+		t.Fatal("BatchUpdate succeeded against a failing server")
+	}
+	return atomic.LoadInt32(&attempts)
+}
+
+func TestClientBatchUpdateRetriesIdempotentRequests(t *testing.T) {
+	requests := []*sheets.Request{{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range:  &sheets.GridRange{SheetId: 1},
+			Cell:   &sheets.CellData{UserEnteredFormat: &sheets.CellFormat{HorizontalAlignment: "CENTER"}},
+			Fields: "userEnteredFormat.horizontalAlignment",
+		},
+	}}
+	if got := countBatchUpdateAttempts(t, requests); got != 3 {
+		t.Errorf("sent %d times, want 3", got)
+	}
+}
+
+// 行の挿入やシートの追加を含む BatchUpdate は、適用済みの可能性がある 5xx では再送しない
+func TestClientBatchUpdateDoesNotRetryNonIdempotentRequests(t *testing.T) {
+	for name, request := range map[string]*sheets.Request{
+		"InsertDimension": {InsertDimension: &sheets.InsertDimensionRequest{Range: &sheets.DimensionRange{SheetId: 1, Dimension: "ROWS", StartIndex: 3, EndIndex: 4}}},
+		"AddSheet":        {AddSheet: &sheets.AddSheetRequest{Properties: &sheets.SheetProperties{Title: "2024年4月"}}},
+		"AddFilterView":   {AddFilterView: &sheets.AddFilterViewRequest{Filter: &sheets.FilterView{Title: "早番"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := countBatchUpdateAttempts(t, []*sheets.Request{request}); got != 1 {
+				t.Errorf("sent %d times, want 1", got)
+			}
+		})
+	}
+}
+
+// 作成後に -value-input の設定が変わっても、作成時の入力方法で書き込む
+func TestClientUpdateValuesUsesInputOptionFromCreation(t *testing.T) {
+	defer func(option string) { valueInputOption = option }(valueInputOption)
+
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.Query().Get("valueInputOption"))
+		writeJSON(w, &sheets.UpdateValuesResponse{})
+	}))
+	t.Cleanup(server.Close)
+	srv, err := sheets.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valueInputOption = "USER_ENTERED"
+	client := newClient(srv, defaultRetryConfig, 0)
+	valueInputOption = "RAW"
+
+	ctx := context.Background()
+	if _, err := client.UpdateValues(ctx, "fake", "Sheet1!A1", [][]interface{}{{"=1+1"}}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UpdateValues(ctx, "fake", "Sheet1!A1", [][]interface{}{{"=1+1"}}, "RAW"); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != "USER_ENTERED" || got[1] != "RAW" {
+		t.Errorf("valueInputOption sent = %q, want [USER_ENTERED RAW]", got)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 値の大小に応じて背景色を段階的に変える条件付き書式（カラースケール）を追加
+// 中間点（50 パーセンタイル）は最小色と最大色の中間の色にする
+func addGradientRule(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange, minColor *sheets.Color, maxColor *sheets.Color) error {
+	if err := validateGridRange(gridRange); err != nil {
+		return err
+	}
+	for _, c := range []*sheets.Color{minColor, maxColor} {
+		if err := validateColor(c); err != nil {
+			return err
+		}
+	}
+
+	midColor := &sheets.Color{
+		Red:   (minColor.Red + maxColor.Red) / 2,
+		Green: (minColor.Green + maxColor.Green) / 2,
+		Blue:  (minColor.Blue + maxColor.Blue) / 2,
+	}
+
+	addRuleRequest := sheets.Request{
+		AddConditionalFormatRule: &sheets.AddConditionalFormatRuleRequest{
+			Rule: &sheets.ConditionalFormatRule{
+				Ranges: []*sheets.GridRange{gridRange},
+				GradientRule: &sheets.GradientRule{
+					Minpoint: &sheets.InterpolationPoint{Type: "MIN", Color: minColor},
+					Midpoint: &sheets.InterpolationPoint{Type: "PERCENTILE", Value: "50", Color: midColor},
+					Maxpoint: &sheets.InterpolationPoint{Type: "MAX", Color: maxColor},
+				},
+			},
+			Index: 0,
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&addRuleRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// シートの条件付き書式のルールを優先順（index の順）に取得（ない場合は空のスライス）
+func getConditionalFormats(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64) ([]*sheets.ConditionalFormatRule, error) {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("sheets(properties.sheetId,conditionalFormats)").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.SheetId != sheetId {
+			continue
+		}
+		if sheet.ConditionalFormats == nil {
+			return []*sheets.ConditionalFormatRule{}, nil
+		}
+		return sheet.ConditionalFormats, nil
+	}
+
+	return nil, fmt.Errorf("sheet id %d not found in spreadsheet %s", sheetId, spreadsheetId)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// Sheets は数値を倍精度で保持するため、これより桁数の多い数字は数値にしない
+const maxCSVNumberDigits = 15
+
+// CSV のフィールドを書き込む値に変換
+// 数値にするのは csvField で書き出したときに元と同じ文字列に戻る場合だけで、
+// "09"、"+5"、"1.50"、"-0"、"1e3" や16桁以上の社員番号などは文字列のまま残す
+func csvFieldValue(field string) interface{} {
+	if len(strings.NewReplacer("-", "", ".", "").Replace(field)) > maxCSVNumberDigits {
+		return field
+	}
+	if i, err := strconv.ParseInt(field, 10, 64); err == nil {
+		if strconv.FormatInt(i, 10) == field {
+			return i
+		}
+		return field
+	}
+	// 整数として読めないものは小数点を含む場合だけ数値にする（"NaN" や "-Inf" は除く）
+	if !strings.Contains(field, ".") {
+		return field
+	}
+	if f, err := strconv.ParseFloat(field, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == field {
+		return f
+	}
+	return field
+}
+
+// 読み取った値を CSV のフィールドに変換（数値は指数表記にしない）
+func csvField(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// CSV を読み込んで範囲に書き込み
+func importCSV(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string, r io.Reader) (UpdateResult, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return UpdateResult{}, fmt.Errorf("unable to parse csv: %w", err)
+	}
+
+	values := make([][]interface{}, len(records))
+	for i, record := range records {
+		values[i] = make([]interface{}, len(record))
+		for j, field := range record {
+			values[i][j] = csvFieldValue(field)
+		}
+	}
+
+	return writeRange(ctx, srv, spreadsheetId, a1, values)
+}
+
+// 範囲の値を CSV として書き出し
+func exportCSV(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string, w io.Writer) error {
+	a1, err := qualifyRange(a1)
+	if err != nil {
+		return err
+	}
+
+	valueRange, err := srv.Spreadsheets.Values.Get(spreadsheetId, a1).ValueRenderOption("UNFORMATTED_VALUE").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return writeCSV(w, valueRange.Values)
+}
+
+// 値を CSV として書き出し
+func writeCSV(w io.Writer, values [][]interface{}) error {
+	writer := csv.NewWriter(w)
+	for _, row := range values {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = csvField(v)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
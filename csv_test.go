@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVFieldValue(t *testing.T) {
+	tests := []struct {
+		field string
+		want  interface{}
+	}{
+		{field: "", want: ""},
+		{field: "8", want: int64(8)},
+		{field: "-12", want: int64(-12)},
+		{field: "0", want: int64(0)},
+		{field: "7.5", want: 7.5},
+		{field: "0.25", want: 0.25},
+		{field: "-0.5", want: -0.5},
+		{field: "09", want: "09"},
+		{field: "-09", want: "-09"},
+		{field: "0123", want: "0123"},
+		{field: "1e3", want: "1e3"},
+		{field: "1E3", want: "1E3"},
+		{field: "NaN", want: "NaN"},
+		{field: "Inf", want: "Inf"},
+		{field: "-Inf", want: "-Inf"},
+		{field: "+5", want: "+5"},
+		{field: "1.50", want: "1.50"},
+		{field: "1.0", want: "1.0"},
+		{field: ".5", want: ".5"},
+		{field: "-0", want: "-0"},
+		{field: "-0.0", want: "-0.0"},
+		{field: "123456789012345", want: int64(123456789012345)},
+		{field: "12345678901234567", want: "12345678901234567"},
+		{field: "1152921504606846976", want: "1152921504606846976"},
+		{field: "0.1234567890123456", want: "0.1234567890123456"},
+		{field: "山田, 太郎", want: "山田, 太郎"},
+		{field: "line1\nline2", want: "line1\nline2"},
+		{field: "9:00", want: "9:00"},
+	}
+	for _, tt := range tests {
+		got := csvFieldValue(tt.field)
+		if got != tt.want {
+			t.Errorf("csvFieldValue(%q) = %#v, want %#v", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestCSVField(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{v: nil, want: ""},
+		{v: "", want: ""},
+		{v: float64(8), want: "8"},
+		{v: 7.5, want: "7.5"},
+		{v: 1e21, want: "1000000000000000000000"},
+		{v: 0.000001, want: "0.000001"},
+		{v: int64(-3), want: "-3"},
+		{v: true, want: "true"},
+		{v: "09", want: "09"},
+	}
+	for _, tt := range tests {
+		if got := csvField(tt.v); got != tt.want {
+			t.Errorf("csvField(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+// フィールドを値に変換し、API から返る形（数値は float64）を経て書き出すと元のフィールドに戻る
+func TestCSVFieldRoundTrip(t *testing.T) {
+	for _, field := range []string{"", "8", "-12", "7.5", "09", "0123", "1e3", "+5", "1.50", "-0", "-0.0", "12345678901234567", "山田, 太郎", "a \"quoted\" word", "line1\nline2"} {
+		v := csvFieldValue(field)
+		if i, ok := v.(int64); ok {
+			v = float64(i)
+		}
+		if got := csvField(v); got != field {
+			t.Errorf("round trip of %q = %q", field, got)
+		}
+	}
+}
+
+// importCSV で書き込んだ範囲を exportCSV で読み戻すと同じ CSV になる
+func TestImportExportCSVRoundTrip(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+	ctx := context.Background()
+
+	input := "名前,勤務時間,社員番号,備考\n" +
+		"\"山田, 太郎\",7.5,09,\"早番\n遅番\"\n" +
+		"佐藤,8,0123,\n" +
+		",,,\"\"\"引用\"\"\"\n" +
+		"+5,1.50,-0,12345678901234567\n"
+
+	result, err := importCSV(ctx, srv, "fake", "Sheet1!A1", strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("importCSV: %v", err)
+	}
+	if result.UpdatedCells != 20 {
+		t.Errorf("UpdatedCells = %d, want 20", result.UpdatedCells)
+	}
+	if got := fake.values["Sheet1"][2][1]; got != float64(8) {
+		t.Errorf("8 was written as %#v, want a number", got)
+	}
+	if got := fake.values["Sheet1"][1][2]; got != "09" {
+		t.Errorf("09 was written as %#v, want the string \"09\"", got)
+	}
+
+	var out bytes.Buffer
+	if err := exportCSV(ctx, srv, "fake", "Sheet1!A1:D5", &out); err != nil {
+		t.Fatalf("exportCSV: %v", err)
+	}
+
+	want, err := csv.NewReader(strings.NewReader(input)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := csv.NewReader(&out)
+	reader.FieldsPerRecord = -1
+	got, err := reader.ReadAll()
+	if err != nil {
+		t.Fatalf("exported csv is invalid: %v\n%s", err, out.String())
+	}
+	// 末尾の空セルは API と同様に省略されるため、比較前にそろえる
+	for i := range got {
+		for len(got[i]) < len(want[i]) {
+			got[i] = append(got[i], "")
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch\n got: %q\nwant: %q", got, want)
+	}
+}
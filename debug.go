@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// true の場合、詳細なログを出力する
+var debug bool
+
+// true の場合、認証まわりの案内を標準出力ではなくデバッグログに出す
+var quiet bool
+
+// デバッグ用のログを出力（-debug 指定時のみ）
+func debugf(format string, args ...interface{}) {
+	if debug {
+		log.Printf("debug: "+format, args...)
+	}
+}
+
+// 案内メッセージを標準出力に表示（-quiet 指定時はデバッグログに回す）
+func infof(format string, args ...interface{}) {
+	if quiet {
+		debugf(strings.TrimSuffix(format, "\n"), args...)
+		return
+	}
+	fmt.Printf(format, args...)
+}
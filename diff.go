@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// true の場合、書き込みを行わず変更内容を表示するだけにする
+var dryRun bool
+
+// セル単位の変更内容
+type cellDiff struct {
+	Row    int64
+	Column int64
+	Old    string
+	New    string
+}
+
+func cellString(rows [][]interface{}, r int, c int) string {
+	if r >= len(rows) || c >= len(rows[r]) || rows[r][c] == nil {
+		return ""
+	}
+	return fmt.Sprint(rows[r][c])
+}
+
+// 現在の値と書き込む予定の値をセルごとに比較（行・列は範囲の左上からの0始まり）
+func diffValues(current [][]interface{}, intended [][]interface{}) []cellDiff {
+	var diffs []cellDiff
+	for r, row := range intended {
+		for c := range row {
+			oldValue, newValue := cellString(current, r, c), cellString(intended, r, c)
+			if oldValue != newValue {
+				diffs = append(diffs, cellDiff{Row: int64(r), Column: int64(c), Old: oldValue, New: newValue})
+			}
+		}
+	}
+	return diffs
+}
+
+// 範囲に書き込んだ場合の変更内容を読み取って表示（書き込みは行わない）
+func previewWriteRange(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string, values [][]interface{}, w io.Writer) error {
+	r, err := parseA1Range(a1)
+	if err != nil {
+		return err
+	}
+
+	current, err := srv.Spreadsheets.Values.Get(spreadsheetId, a1).ValueRenderOption("UNFORMATTED_VALUE").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	diffs := diffValues(current.Values, values)
+	if len(diffs) == 0 {
+		fmt.Fprintf(w, "%s: no changes\n", a1)
+		return nil
+	}
+
+	fmt.Fprintf(w, "%s: %d cell(s) would change\n", a1, len(diffs))
+	for _, d := range diffs {
+		fmt.Fprintf(w, "  %s: %q -> %q\n", cellA1(r.Sheet, r.StartRow+d.Row, r.StartColumn+d.Column), d.Old, d.New)
+	}
+	return nil
+}
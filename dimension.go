@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 行・列の範囲（0始まりの半開区間）を作成して検証
+func dimensionRange(sheetId int64, dimension string, start int64, end int64) (*sheets.DimensionRange, error) {
+	if dimension != "ROWS" && dimension != "COLUMNS" {
+		return nil, fmt.Errorf("invalid dimension %q: must be ROWS or COLUMNS", dimension)
+	}
+	if start < 0 || end <= start {
+		return nil, fmt.Errorf("invalid %s range [%d, %d)", dimension, start, end)
+	}
+
+	return &sheets.DimensionRange{
+		SheetId:    sheetId,
+		Dimension:  dimension,
+		StartIndex: start,
+		EndIndex:   end,
+	}, nil
+}
+
+// 行・列をグループ化して折りたためるようにする（部署ごとのまとまりなど）
+func addDimensionGroup(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, dimension string, start int64, end int64) error {
+	dr, err := dimensionRange(sheetId, dimension, start, end)
+	if err != nil {
+		return err
+	}
+
+	addGroupRequest := sheets.Request{
+		AddDimensionGroup: &sheets.AddDimensionGroupRequest{
+			Range: dr,
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&addGroupRequest},
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 行・列のグループを解除
+func deleteDimensionGroup(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, dimension string, start int64, end int64) error {
+	dr, err := dimensionRange(sheetId, dimension, start, end)
+	if err != nil {
+		return err
+	}
+
+	deleteGroupRequest := sheets.Request{
+		DeleteDimensionGroup: &sheets.DeleteDimensionGroupRequest{
+			Range: dr,
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&deleteGroupRequest},
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// スプレッドシートをユーザーに共有し、作成された権限のIDを返す
+// role は reader / commenter / writer / owner（owner はオーナー権限の移譲）
+func shareSpreadsheet(ctx context.Context, driveSrv *drive.Service, fileId string, email string, role string, notify bool) (string, error) {
+	switch role {
+	case "reader", "commenter", "writer", "owner":
+	default:
+		return "", fmt.Errorf("invalid role %q: must be reader, commenter, writer or owner", role)
+	}
+	if email == "" {
+		return "", fmt.Errorf("email is required")
+	}
+
+	permission := &drive.Permission{
+		Type:         "user",
+		Role:         role,
+		EmailAddress: email,
+	}
+
+	call := driveSrv.Permissions.Create(fileId, permission).SendNotificationEmail(notify).Context(ctx)
+	if role == "owner" {
+		// オーナーの移譲では通知メールの送信が必須
+		call = call.TransferOwnership(true).SendNotificationEmail(true)
+	}
+
+	created, err := call.Do()
+	if err != nil {
+		return "", err
+	}
+
+	return created.Id, nil
+}
+
+// スプレッドシートのファイルごと複製し、新しいファイルのIDを返す
+// シートごとの CopyTo と違い、書式・保護範囲・名前付き範囲などもすべてそのまま複製される
+// drive.file スコープでは、このアプリで作成または開いたファイルしか複製できない点に注意
+func copySpreadsheetFile(ctx context.Context, driveSrv *drive.Service, sourceId string, newTitle string) (string, error) {
+	if sourceId == "" {
+		return "", fmt.Errorf("source spreadsheet id is required")
+	}
+	if newTitle == "" {
+		return "", fmt.Errorf("new title is required")
+	}
+
+	copied, err := driveSrv.Files.Copy(sourceId, &drive.File{Name: newTitle}).Fields("id").Context(ctx).Do()
+	if err != nil {
+		return "", err
+	}
+
+	return copied.Id, nil
+}
+
+// Excel ファイルの MIME タイプ
+const xlsxMimeType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// ローカルの .xlsx をアップロードしてスプレッドシートに変換し、作成されたスプレッドシートのIDを返す
+// Excel で作ったテンプレートから始める場合に使う
+// 大きなファイルはチャンクサイズ（既定 16MB）ごとの再開可能なアップロードで送信される
+func importXLSX(ctx context.Context, driveSrv *drive.Service, path string, title string) (string, error) {
+	if !strings.EqualFold(filepath.Ext(path), ".xlsx") {
+		return "", fmt.Errorf("%s is not an .xlsx file", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	file := &drive.File{
+		Name: title,
+		// Google スプレッドシートの MIME タイプを指定するとアップロード時に変換される
+		MimeType: "application/vnd.google-apps.spreadsheet",
+	}
+
+	created, err := driveSrv.Files.Create(file).Media(f, googleapi.ContentType(xlsxMimeType)).Fields("id").Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("unable to import %s: %w", path, err)
+	}
+
+	return created.Id, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/width"
+)
+
+// 表示時に1セルへ出す最大の幅（半角文字数）
+const maxCellDisplayWidth = 30
+
+// 端末上の表示幅（全角文字は2として数える）
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// 表示幅が max を超える場合は末尾を省略記号に置き換える
+func truncateDisplay(s string, max int) string {
+	if displayWidth(s) <= max {
+		return s
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		w := displayWidth(string(r))
+		if n+w > max-1 {
+			break
+		}
+		b.WriteRune(r)
+		n += w
+	}
+	return b.String() + "…"
+}
+
+// 値を列幅をそろえた表として出力
+func renderTable(w io.Writer, values [][]interface{}) error {
+	var rows [][]string
+	var widths []int
+	for _, row := range values {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = truncateDisplay(fmt.Sprint(v), maxCellDisplayWidth)
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if cw := displayWidth(cells[i]); cw > widths[i] {
+				widths[i] = cw
+			}
+		}
+		rows = append(rows, cells)
+	}
+
+	border := "+"
+	for _, cw := range widths {
+		border += strings.Repeat("-", cw+2) + "+"
+	}
+
+	if _, err := fmt.Fprintln(w, border); err != nil {
+		return err
+	}
+	for _, cells := range rows {
+		line := "|"
+		for i, cw := range widths {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			line += " " + cell + strings.Repeat(" ", cw-displayWidth(cell)) + " |"
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, border)
+	return err
+}
+
+// シートの名前とID（-output json で出力）
+type sheetSummary struct {
+	Title string `json:"title"`
+	Id    int64  `json:"id"`
+}
+
+// 生成結果をJSONで出力
+func writeGenerateResultJSON(w io.Writer, result *GenerateResult) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// 値を行ごとの配列のJSONとして出力
+func exportJSON(w io.Writer, values [][]interface{}) error {
+	if values == nil {
+		values = [][]interface{}{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(values)
+}
+
+// Markdown の表のセルとして使えるように "|" と改行をエスケープ
+func markdownCell(v interface{}) string {
+	s := fmt.Sprint(v)
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r\n", "<br>")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
+
+// 値を1行目を見出しとした Markdown の表として出力
+func renderMarkdown(w io.Writer, values [][]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	columns := 0
+	for _, row := range values {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+
+	for i, row := range values {
+		cells := make([]string, columns)
+		for j := range cells {
+			if j < len(row) && row[j] != nil {
+				cells[j] = markdownCell(row[j])
+			}
+		}
+		if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+			return err
+		}
+		if i == 0 {
+			separator := make([]string, columns)
+			for j := range separator {
+				separator[j] = "---"
+			}
+			if _, err := fmt.Fprintf(w, "| %s |\n", strings.Join(separator, " | ")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 読み取った値を format（table / csv / json / markdown）の形式で出力
+// どの形式も同じ値（表示形式を適用した文字列）を出力するよう、読み取りは呼び出し側で1回だけ行う
+func renderValues(w io.Writer, values [][]interface{}, format string) error {
+	switch format {
+	case "table":
+		return renderTable(w, values)
+	case "csv":
+		return writeCSV(w, values)
+	case "json":
+		return exportJSON(w, values)
+	case "markdown":
+		return renderMarkdown(w, values)
+	}
+	return fmt.Errorf("invalid format %q: must be table, csv, json or markdown", format)
+}
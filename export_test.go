@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// どの形式でも同じ表示形式の値を出力する
+func TestRenderValuesUsesSameValues(t *testing.T) {
+	values := [][]interface{}{
+		{"日付", "勤務時間"},
+		{"2024/04/01", "8:00"},
+	}
+	for _, format := range []string{"table", "csv", "json", "markdown"} {
+		var out bytes.Buffer
+		if err := renderValues(&out, values, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		for _, want := range []string{"2024/04/01", "8:00"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%s output does not contain %q:\n%s", format, want, out.String())
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := renderValues(&out, values, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "日付,勤務時間\n2024/04/01,8:00\n"; out.String() != want {
+		t.Errorf("csv = %q, want %q", out.String(), want)
+	}
+
+	if err := renderValues(&out, values, "xml"); err == nil {
+		t.Error("renderValues accepted an unknown format")
+	}
+}
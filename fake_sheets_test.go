@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// テスト用の Sheets API の簡易サーバー
+// スプレッドシートの取得、値の読み書き・クリア、BatchUpdate の記録、メタデータの検索だけを扱う
+type fakeSheets struct {
+	mu sync.Mutex
+	// Spreadsheets.Get で返すスプレッドシート（fields の指定は無視する）
+	spreadsheet *sheets.Spreadsheet
+	// シート名ごとの値（左上が A1）
+	values map[string][][]interface{}
+	// 受け取った BatchUpdate のリクエスト
+	requests []*sheets.Request
+	// 受け取った Values.Clear の範囲
+	cleared []string
+	// Add* のリクエストに返す次のID
+	nextId int64
+	// スプレッドシート単位のメタデータ（キーと値）
+	metadata map[string]string
+	// 受け取ったAPI呼び出しの回数
+	calls int
+}
+
+// 1枚のシートを持つ fakeSheets を作成
+func newFakeSheets(title string, rows int64, columns int64) *fakeSheets {
+	return &fakeSheets{
+		spreadsheet: &sheets.Spreadsheet{
+			SpreadsheetId: "fake",
+			Sheets: []*sheets.Sheet{{
+				Properties: &sheets.SheetProperties{
+					SheetId:        1,
+					Title:          title,
+					GridProperties: &sheets.GridProperties{RowCount: rows, ColumnCount: columns},
+				},
+			}},
+		},
+		values:   map[string][][]interface{}{},
+		metadata: map[string]string{},
+	}
+}
+
+// fakeSheets に接続する Service を作成
+func (f *fakeSheets) service(t *testing.T) *sheets.Service {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	srv, err := sheets.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatalf("unable to create sheets service: %v", err)
+	}
+	return srv
+}
+
+func (f *fakeSheets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+
+	path := strings.TrimPrefix(r.URL.Path, "/v4/spreadsheets/"+f.spreadsheet.SpreadsheetId)
+	switch {
+	case r.Method == http.MethodGet && path == "":
+		writeJSON(w, f.spreadsheet)
+	case r.Method == http.MethodPost && path == ":batchUpdate":
+		var req sheets.BatchUpdateSpreadsheetRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		f.requests = append(f.requests, req.Requests...)
+		replies := make([]*sheets.Response, len(req.Requests))
+		for i, request := range req.Requests {
+			f.apply(request)
+			replies[i] = f.reply(request)
+		}
+		writeJSON(w, &sheets.BatchUpdateSpreadsheetResponse{
+			SpreadsheetId: f.spreadsheet.SpreadsheetId,
+			Replies:       replies,
+		})
+	case r.Method == http.MethodPost && path == "/developerMetadata:search":
+		var req sheets.SearchDeveloperMetadataRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		resp := &sheets.SearchDeveloperMetadataResponse{}
+		for _, filter := range req.DataFilters {
+			key := filter.DeveloperMetadataLookup.MetadataKey
+			if value, ok := f.metadata[key]; ok {
+				resp.MatchedDeveloperMetadata = append(resp.MatchedDeveloperMetadata, &sheets.MatchedDeveloperMetadata{
+					DeveloperMetadata: &sheets.DeveloperMetadata{MetadataKey: key, MetadataValue: value},
+				})
+			}
+		}
+		writeJSON(w, resp)
+	case r.Method == http.MethodPost && path == "/values:batchUpdate":
+		var req sheets.BatchUpdateValuesRequest
+		if !readJSON(w, r, &req) {
+			return
+		}
+		for _, data := range req.Data {
+			f.write(data.Range, data.Values)
+		}
+		writeJSON(w, &sheets.BatchUpdateValuesResponse{SpreadsheetId: f.spreadsheet.SpreadsheetId})
+	case r.Method == http.MethodGet && path == "/values:batchGet":
+		resp := &sheets.BatchGetValuesResponse{SpreadsheetId: f.spreadsheet.SpreadsheetId}
+		for _, a1 := range r.URL.Query()["ranges"] {
+			resp.ValueRanges = append(resp.ValueRanges, &sheets.ValueRange{Range: a1, Values: f.read(a1)})
+		}
+		writeJSON(w, resp)
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "/values/") && strings.HasSuffix(path, ":clear"):
+		a1 := strings.TrimSuffix(strings.TrimPrefix(path, "/values/"), ":clear")
+		f.cleared = append(f.cleared, a1)
+		f.clear(a1)
+		writeJSON(w, &sheets.ClearValuesResponse{ClearedRange: a1})
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "/values/"):
+		var req sheets.ValueRange
+		if !readJSON(w, r, &req) {
+			return
+		}
+		a1 := strings.TrimPrefix(path, "/values/")
+		f.write(a1, req.Values)
+		cells := 0
+		for _, row := range req.Values {
+			cells += len(row)
+		}
+		writeJSON(w, &sheets.UpdateValuesResponse{UpdatedRange: a1, UpdatedRows: int64(len(req.Values)), UpdatedCells: int64(cells)})
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/values/"):
+		a1 := strings.TrimPrefix(path, "/values/")
+		writeJSON(w, &sheets.ValueRange{Range: a1, Values: f.read(a1)})
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+	}
+}
+
+// BatchUpdate のリクエストのうち、シートの状態を変えるものを反映する
+// 条件付き書式のルールの追加・削除とメタデータの作成・更新のほかは記録するだけ
+func (f *fakeSheets) apply(request *sheets.Request) {
+	switch {
+	case request.CreateDeveloperMetadata != nil:
+		metadata := request.CreateDeveloperMetadata.DeveloperMetadata
+		f.metadata[metadata.MetadataKey] = metadata.MetadataValue
+		return
+	case request.UpdateDeveloperMetadata != nil:
+		for _, filter := range request.UpdateDeveloperMetadata.DataFilters {
+			key := filter.DeveloperMetadataLookup.MetadataKey
+			if _, ok := f.metadata[key]; ok {
+				f.metadata[key] = request.UpdateDeveloperMetadata.DeveloperMetadata.MetadataValue
+			}
+		}
+		return
+	}
+	for _, sheet := range f.spreadsheet.Sheets {
+		switch {
+		case request.AddConditionalFormatRule != nil:
+			add := request.AddConditionalFormatRule
+			if len(add.Rule.Ranges) == 0 || add.Rule.Ranges[0].SheetId != sheet.Properties.SheetId {
+				continue
+			}
+			index := int(add.Index)
+			if index > len(sheet.ConditionalFormats) {
+				index = len(sheet.ConditionalFormats)
+			}
+			sheet.ConditionalFormats = append(sheet.ConditionalFormats[:index], append([]*sheets.ConditionalFormatRule{add.Rule}, sheet.ConditionalFormats[index:]...)...)
+		case request.DeleteConditionalFormatRule != nil:
+			del := request.DeleteConditionalFormatRule
+			if del.SheetId != sheet.Properties.SheetId || int(del.Index) >= len(sheet.ConditionalFormats) {
+				continue
+			}
+			sheet.ConditionalFormats = append(sheet.ConditionalFormats[:del.Index], sheet.ConditionalFormats[del.Index+1:]...)
+		}
+	}
+}
+
+// フィルタ表示・保護範囲・グラフの追加には、IDを割り当てた返信を返す（ほかは空の返信）
+func (f *fakeSheets) reply(request *sheets.Request) *sheets.Response {
+	switch {
+	case request.AddChart != nil:
+		f.nextId++
+		chart := *request.AddChart.Chart
+		chart.ChartId = f.nextId
+		return &sheets.Response{AddChart: &sheets.AddChartResponse{Chart: &chart}}
+	case request.AddFilterView != nil:
+		f.nextId++
+		filter := *request.AddFilterView.Filter
+		filter.FilterViewId = f.nextId
+		return &sheets.Response{AddFilterView: &sheets.AddFilterViewResponse{Filter: &filter}}
+	case request.AddProtectedRange != nil:
+		f.nextId++
+		protected := *request.AddProtectedRange.ProtectedRange
+		protected.ProtectedRangeId = f.nextId
+		return &sheets.Response{AddProtectedRange: &sheets.AddProtectedRangeResponse{ProtectedRange: &protected}}
+	}
+	return &sheets.Response{}
+}
+
+// 範囲の左上から値を書き込む
+func (f *fakeSheets) write(a1 string, values [][]interface{}) {
+	r, _ := parseA1Range(a1)
+	grid := f.values[r.Sheet]
+	for i, row := range values {
+		y := int(r.StartRow) + i
+		for len(grid) <= y {
+			grid = append(grid, nil)
+		}
+		for j, v := range row {
+			x := int(r.StartColumn) + j
+			for len(grid[y]) <= x {
+				grid[y] = append(grid[y], "")
+			}
+			grid[y][x] = v
+		}
+	}
+	f.values[r.Sheet] = grid
+}
+
+// 範囲の値を読み取る（APIと同様に末尾の空の行・セルは省略する）
+func (f *fakeSheets) read(a1 string) [][]interface{} {
+	r, _ := parseA1Range(a1)
+	grid := f.values[r.Sheet]
+	endRow, endColumn := int64(len(grid)), int64(1<<31)
+	if r.hasRows && !r.openEndRow {
+		endRow = r.EndRow
+	}
+	if r.hasColumns {
+		endColumn = r.EndColumn
+	}
+
+	var values [][]interface{}
+	for y := r.StartRow; y < endRow && y < int64(len(grid)); y++ {
+		var row []interface{}
+		for x := r.StartColumn; x < endColumn && x < int64(len(grid[y])); x++ {
+			row = append(row, grid[y][x])
+		}
+		for len(row) > 0 && row[len(row)-1] == "" {
+			row = row[:len(row)-1]
+		}
+		values = append(values, row)
+	}
+	for len(values) > 0 && len(values[len(values)-1]) == 0 {
+		values = values[:len(values)-1]
+	}
+	return values
+}
+
+// 範囲の値を空にする
+func (f *fakeSheets) clear(a1 string) {
+	r, _ := parseA1Range(a1)
+	grid := f.values[r.Sheet]
+	for y := range grid {
+		if r.hasRows && (int64(y) < r.StartRow || (!r.openEndRow && int64(y) >= r.EndRow)) {
+			continue
+		}
+		for x := range grid[y] {
+			if r.hasColumns && (int64(x) < r.StartColumn || int64(x) >= r.EndColumn) {
+				continue
+			}
+			grid[y][x] = ""
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
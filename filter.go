@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 名前付きのフィルタ表示を追加し、作成されたフィルタ表示のIDを返す
+// 基本のフィルタと違い他の閲覧者の表示に影響しないため、共有している勤務表で使う
+func addFilterView(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, title string, gridRange *sheets.GridRange) (int64, error) {
+	if title == "" {
+		return 0, fmt.Errorf("filter view title is required")
+	}
+	gr, err := onSheet(gridRange, sheetId)
+	if err != nil {
+		return 0, err
+	}
+
+	addFilterViewRequest := sheets.Request{
+		AddFilterView: &sheets.AddFilterViewRequest{
+			Filter: &sheets.FilterView{
+				Title: title,
+				Range: gr,
+			},
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&addFilterViewRequest},
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	return addedFilterViewId(resp.Replies, 0)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// フィルタ表示のリクエストには対象のシートのIDを設定し、呼び出し側の範囲は変更しない
+func TestAddFilterViewKeepsCallerRange(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+
+	gridRange := &sheets.GridRange{SheetId: 99, StartRowIndex: 3, EndRowIndex: 10}
+	id, err := addFilterView(context.Background(), srv, "fake", 1, "早番", gridRange)
+	if err != nil {
+		t.Fatalf("addFilterView: %v", err)
+	}
+	if id == 0 {
+		t.Error("filter view ID was not returned")
+	}
+
+	if gridRange.SheetId != 99 {
+		t.Errorf("caller's range sheet ID = %d, want 99", gridRange.SheetId)
+	}
+	if got := fake.requests[0].AddFilterView.Filter.Range.SheetId; got != 1 {
+		t.Errorf("filter view range sheet ID = %d, want 1", got)
+	}
+}
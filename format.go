@@ -0,0 +1,570 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// ヘッダー行の固定・太字・中央揃えを1回のBatchUpdateで適用
+func formatHeader(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, headerRows int) error {
+	if headerRows <= 0 {
+		return fmt.Errorf("headerRows must be positive, got %d", headerRows)
+	}
+
+	freezeRequest := sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId: sheetId,
+				GridProperties: &sheets.GridProperties{
+					FrozenRowCount: int64(headerRows),
+				},
+			},
+			Fields: "gridProperties.frozenRowCount",
+		},
+	}
+
+	styleRequest := headerStyleRequest(&sheets.GridRange{
+		SheetId:       sheetId,
+		StartRowIndex: 0,
+		EndRowIndex:   int64(headerRows),
+	}, nil)
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&freezeRequest, styleRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ヘッダーの範囲を太字・中央揃えにするリクエストを作成（color が nil でなければ背景色も付ける）
+func headerStyleRequest(gridRange *sheets.GridRange, color *sheets.Color) *sheets.Request {
+	format := &sheets.CellFormat{
+		TextFormat:          &sheets.TextFormat{Bold: true},
+		HorizontalAlignment: "CENTER",
+	}
+	fields := "userEnteredFormat.textFormat.bold,userEnteredFormat.horizontalAlignment"
+	if color != nil {
+		format.BackgroundColor = color
+		fields += ",userEnteredFormat.backgroundColor"
+	}
+	return &sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range:  gridRange,
+			Cell:   &sheets.CellData{UserEnteredFormat: format},
+			Fields: fields,
+		},
+	}
+}
+
+// セル内のテキストの折り返し方法を設定（OVERFLOW_CELL / CLIP / WRAP）
+func setWrapStrategy(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange, strategy string) error {
+	switch strategy {
+	case "OVERFLOW_CELL", "CLIP", "WRAP":
+	default:
+		return fmt.Errorf("invalid wrap strategy %q: must be OVERFLOW_CELL, CLIP or WRAP", strategy)
+	}
+	if err := validateGridRange(gridRange); err != nil {
+		return err
+	}
+
+	repeatCellRequest := sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range: gridRange,
+			Cell: &sheets.CellData{
+				UserEnteredFormat: &sheets.CellFormat{
+					WrapStrategy: strategy,
+				},
+			},
+			Fields: "userEnteredFormat.wrapStrategy",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&repeatCellRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 行の交互の背景色（縞模様）を追加し、作成されたバンドのIDを返す
+func addBanding(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange, headerColor *sheets.Color, bandColor *sheets.Color) (int64, error) {
+	if err := validateGridRange(gridRange); err != nil {
+		return 0, err
+	}
+	for _, c := range []*sheets.Color{headerColor, bandColor} {
+		if err := validateColor(c); err != nil {
+			return 0, err
+		}
+	}
+
+	addBandingRequest := sheets.Request{
+		AddBanding: &sheets.AddBandingRequest{
+			BandedRange: &sheets.BandedRange{
+				Range: gridRange,
+				RowProperties: &sheets.BandingProperties{
+					HeaderColor:     headerColor,
+					FirstBandColor:  &sheets.Color{Red: 1, Green: 1, Blue: 1},
+					SecondBandColor: bandColor,
+				},
+			},
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&addBandingRequest},
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	return addedBandedRangeId(resp.Replies, 0)
+}
+
+// 色の各チャンネルが 0〜1 の範囲にあるかを検証
+func validateColor(c *sheets.Color) error {
+	if c == nil {
+		return fmt.Errorf("color is required")
+	}
+	for _, ch := range []struct {
+		name  string
+		value float64
+	}{{"red", c.Red}, {"green", c.Green}, {"blue", c.Blue}} {
+		if ch.value < 0 || ch.value > 1 {
+			return fmt.Errorf("color %s channel %v is out of range [0, 1]", ch.name, ch.value)
+		}
+	}
+	return nil
+}
+
+// RGB（各 0〜1）から色を作成
+func rgbColor(rgb [3]float64) (*sheets.Color, error) {
+	c := &sheets.Color{Red: rgb[0], Green: rgb[1], Blue: rgb[2]}
+	if err := validateColor(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// シートの枠線の表示とタブの色を設定
+func setSheetAppearance(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, hideGridlines bool, tabColor [3]float64) error {
+	color, err := rgbColor(tabColor)
+	if err != nil {
+		return err
+	}
+
+	updateSheetPropertiesRequest := sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId: sheetId,
+				GridProperties: &sheets.GridProperties{
+					HideGridlines: hideGridlines,
+					// false の場合も送信しないと枠線を再表示できない
+					ForceSendFields: []string{"HideGridlines"},
+				},
+				TabColorStyle: &sheets.ColorStyle{RgbColor: color},
+			},
+			Fields: "gridProperties.hideGridlines,tabColorStyle",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&updateSheetPropertiesRequest},
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 範囲内の文字列を一括置換し、置換した件数を返す（gridRange が nil の場合は全シート）
+// searchByRegex が true の場合、find は正規表現として扱われ replacement で $1 などを参照できる
+func findReplace(ctx context.Context, srv *sheets.Service, spreadsheetId string, find string, replacement string, gridRange *sheets.GridRange, matchCase bool, matchEntireCell bool, searchByRegex bool) (int64, error) {
+	if find == "" {
+		return 0, fmt.Errorf("find must not be empty")
+	}
+
+	findReplaceRequest := &sheets.FindReplaceRequest{
+		Find:            find,
+		Replacement:     replacement,
+		MatchCase:       matchCase,
+		MatchEntireCell: matchEntireCell,
+		SearchByRegex:   searchByRegex,
+		// 空文字への置換（削除）も送信する
+		ForceSendFields: []string{"Replacement"},
+	}
+	if gridRange == nil {
+		findReplaceRequest.AllSheets = true
+	} else {
+		if err := validateGridRange(gridRange); err != nil {
+			return 0, err
+		}
+		findReplaceRequest.Range = gridRange
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{FindReplace: findReplaceRequest}},
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	return replacedOccurrences(resp.Replies, 0)
+}
+
+// シート全体の基本の書式（フォントや文字サイズなど）を設定
+// 範囲を指定しないためシートの全セルが対象になり、行・列の多いシートでは処理が重くなる点に注意
+// format で指定していない項目は既定値に戻るので、個別の書式はこの後に設定すること
+func setSheetDefaultFormat(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, format *sheets.CellFormat) error {
+	if format == nil {
+		return fmt.Errorf("format is required")
+	}
+
+	repeatCellRequest := sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range: &sheets.GridRange{SheetId: sheetId},
+			Cell: &sheets.CellData{
+				UserEnteredFormat: format,
+			},
+			Fields: "userEnteredFormat",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&repeatCellRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 範囲の表示形式を設定（numberType は NUMBER / DATE / TIME など、pattern は "0.00" などの書式）
+func setNumberFormat(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange, numberType string, pattern string) error {
+	if err := validateGridRange(gridRange); err != nil {
+		return err
+	}
+
+	repeatCellRequest := sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range: gridRange,
+			Cell: &sheets.CellData{
+				UserEnteredFormat: &sheets.CellFormat{
+					NumberFormat: &sheets.NumberFormat{
+						Type:    numberType,
+						Pattern: pattern,
+					},
+				},
+			},
+			Fields: "userEnteredFormat.numberFormat",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&repeatCellRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 24時間を超える勤務時間の合計も "27:30" のように表示されるよう、経過時間の形式を設定
+func setElapsedTimeFormat(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange) error {
+	return setNumberFormat(ctx, srv, spreadsheetId, gridRange, "TIME", "[h]:mm")
+}
+
+// 範囲の書式をすべてクリア（値と、シートに属する条件付き書式のルールはそのまま残す）
+// 新しいスタイルを適用する前に領域をリセットするときに使う
+func clearFormatting(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange) error {
+	if err := validateGridRange(gridRange); err != nil {
+		return err
+	}
+
+	repeatCellRequest := sheets.Request{
+		RepeatCell: &sheets.RepeatCellRequest{
+			Range: gridRange,
+			Cell: &sheets.CellData{
+				UserEnteredFormat: &sheets.CellFormat{},
+			},
+			Fields: "userEnteredFormat",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&repeatCellRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 表示形式を設定する範囲と形式
+type NumberFormatSpec struct {
+	Range   *sheets.GridRange
+	Type    string
+	Pattern string
+}
+
+// 勤務表のシートに適用する書式の定義
+// 範囲の SheetId は ApplyFormat で対象のシートに置き換えられる
+type FormatSpec struct {
+	// 太字・中央揃え・背景色を付ける見出しの範囲（nil の場合は設定しない）
+	HeaderRange *sheets.GridRange
+	HeaderColor *sheets.Color
+
+	// 日付の列の背景色（Year/Month から土日の列を求め、Holidays は祝日の日にち）
+	Year           int
+	Month          int
+	FirstDayColumn int64
+	WeekendColor   *sheets.Color
+	HolidayColor   *sheets.Color
+	Holidays       []int
+
+	// 罫線を引く範囲と線の種類（SOLID / DASHED など、空の場合は引かない）
+	BorderRange *sheets.GridRange
+	BorderStyle string
+
+	FrozenRows    int64
+	FrozenColumns int64
+
+	NumberFormats []NumberFormatSpec
+}
+
+// 勤務表の標準の書式（A列が社員名、B列から日付、最後の列が合計）
+func defaultFormatSpec(year int, month int) (FormatSpec, error) {
+	days, _, err := monthInfo(year, month)
+	if err != nil {
+		return FormatSpec{}, err
+	}
+	totalsColumn := int64(days + 1)
+
+	return FormatSpec{
+		HeaderRange:    &sheets.GridRange{StartRowIndex: 0, EndRowIndex: templateHeaderRows},
+		HeaderColor:    &sheets.Color{Red: 0.85, Green: 0.85, Blue: 0.85},
+		Year:           year,
+		Month:          month,
+		FirstDayColumn: 1,
+		WeekendColor:   &sheets.Color{Red: 0.85, Green: 0.92, Blue: 1},
+		HolidayColor:   &sheets.Color{Red: 1, Green: 0.85, Blue: 0.85},
+		BorderRange:    &sheets.GridRange{StartColumnIndex: 0, EndColumnIndex: totalsColumn + 1},
+		BorderStyle:    "SOLID",
+		FrozenRows:     templateHeaderRows,
+		FrozenColumns:  1,
+		NumberFormats: []NumberFormatSpec{
+			{
+				Range:   &sheets.GridRange{StartRowIndex: templateHeaderRows, StartColumnIndex: totalsColumn, EndColumnIndex: totalsColumn + 1},
+				Type:    "TIME",
+				Pattern: "[h]:mm",
+			},
+		},
+	}, nil
+}
+
+// 範囲をコピーして対象のシートのIDを設定（spec を使い回せるよう元の範囲は変更しない）
+func onSheet(gridRange *sheets.GridRange, sheetId int64) (*sheets.GridRange, error) {
+	if err := validateGridRange(gridRange); err != nil {
+		return nil, err
+	}
+	gr := *gridRange
+	gr.SheetId = sheetId
+	return &gr, nil
+}
+
+// spec の書式を1回のBatchUpdateでシートに適用
+func ApplyFormat(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, spec FormatSpec) error {
+	var requests []*sheets.Request
+
+	if spec.FrozenRows < 0 || spec.FrozenColumns < 0 {
+		return fmt.Errorf("frozen rows and columns must not be negative, got %d and %d", spec.FrozenRows, spec.FrozenColumns)
+	}
+	requests = append(requests, &sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId: sheetId,
+				GridProperties: &sheets.GridProperties{
+					FrozenRowCount:    spec.FrozenRows,
+					FrozenColumnCount: spec.FrozenColumns,
+					// 0（固定の解除）も送信する
+					ForceSendFields: []string{"FrozenRowCount", "FrozenColumnCount"},
+				},
+			},
+			Fields: "gridProperties.frozenRowCount,gridProperties.frozenColumnCount",
+		},
+	})
+
+	if spec.HeaderRange != nil {
+		gr, err := onSheet(spec.HeaderRange, sheetId)
+		if err != nil {
+			return err
+		}
+		if spec.HeaderColor != nil {
+			if err := validateColor(spec.HeaderColor); err != nil {
+				return err
+			}
+		}
+		requests = append(requests, headerStyleRequest(gr, spec.HeaderColor))
+	}
+
+	if spec.WeekendColor != nil || spec.HolidayColor != nil {
+		// ヘッダーの背景色を上書きしないよう、土日・祝日の色はヘッダーより下の行だけに付ける
+		var startRow int64
+		if spec.HeaderRange != nil {
+			startRow = spec.HeaderRange.EndRowIndex
+		}
+		shading, err := nonWorkingDayRequests(sheetId, spec.Year, spec.Month, spec.FirstDayColumn, startRow, spec.WeekendColor, spec.HolidayColor, spec.Holidays)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, shading...)
+	}
+
+	if spec.BorderStyle != "" {
+		if spec.BorderRange == nil {
+			return fmt.Errorf("border range is required when border style is set")
+		}
+		gr, err := onSheet(spec.BorderRange, sheetId)
+		if err != nil {
+			return err
+		}
+		border := &sheets.Border{Style: spec.BorderStyle}
+		requests = append(requests, &sheets.Request{
+			UpdateBorders: &sheets.UpdateBordersRequest{
+				Range:           gr,
+				Top:             border,
+				Bottom:          border,
+				Left:            border,
+				Right:           border,
+				InnerHorizontal: border,
+				InnerVertical:   border,
+			},
+		})
+	}
+
+	for _, nf := range spec.NumberFormats {
+		gr, err := onSheet(nf.Range, sheetId)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, &sheets.Request{
+			RepeatCell: &sheets.RepeatCellRequest{
+				Range: gr,
+				Cell: &sheets.CellData{
+					UserEnteredFormat: &sheets.CellFormat{
+						NumberFormat: &sheets.NumberFormat{Type: nf.Type, Pattern: nf.Pattern},
+					},
+				},
+				Fields: "userEnteredFormat.numberFormat",
+			},
+		})
+	}
+
+	_, err := batchUpdate(ctx, srv, spreadsheetId, requests)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 月の土日と祝日（holidays は日にち）の列に背景色を付けるリクエストを作成（色が nil の方は付けない）
+// 色は startRow 行目（0始まり）から下に付ける（0 の場合は列全体）
+// 土日の後に祝日の背景色を設定するため、土日と祝日が重なる日は祝日の色になる
+func nonWorkingDayRequests(sheetId int64, year int, month int, firstDayColumn int64, startRow int64, weekendColor *sheets.Color, holidayColor *sheets.Color, holidays []int) ([]*sheets.Request, error) {
+	days, firstWeekday, err := monthInfo(year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	var weekends []int
+	for day := 1; day <= days; day++ {
+		switch (int(firstWeekday) + day - 1) % 7 {
+		case int(time.Saturday), int(time.Sunday):
+			weekends = append(weekends, day)
+		}
+	}
+	for _, day := range holidays {
+		if day < 1 || day > days {
+			return nil, fmt.Errorf("holiday %d is out of range 1-%d", day, days)
+		}
+	}
+
+	var requests []*sheets.Request
+	for _, shade := range []struct {
+		color *sheets.Color
+		days  []int
+	}{{weekendColor, weekends}, {holidayColor, holidays}} {
+		if shade.color == nil {
+			continue
+		}
+		if err := validateColor(shade.color); err != nil {
+			return nil, err
+		}
+		for _, day := range shade.days {
+			column := firstDayColumn + int64(day-1)
+			requests = append(requests, &sheets.Request{
+				RepeatCell: &sheets.RepeatCellRequest{
+					Range: &sheets.GridRange{
+						SheetId:          sheetId,
+						StartRowIndex:    startRow,
+						StartColumnIndex: column,
+						EndColumnIndex:   column + 1,
+					},
+					Cell: &sheets.CellData{
+						UserEnteredFormat: &sheets.CellFormat{BackgroundColor: shade.color},
+					},
+					Fields: "userEnteredFormat.backgroundColor",
+				},
+			})
+		}
+	}
+	return requests, nil
+}
+
+// 月の土日と祝日の列を色分けする（B列を1日とし、1回のBatchUpdateで行う）
+func markNonWorkingDays(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, year int, month int, weekendColor *sheets.Color, holidayColor *sheets.Color, holidays []int) error {
+	if weekendColor == nil && holidayColor == nil {
+		return fmt.Errorf("at least one of weekend and holiday colors is required")
+	}
+
+	requests, err := nonWorkingDayRequests(sheetId, year, month, 1, 0, weekendColor, holidayColor, holidays)
+	if err != nil {
+		return err
+	}
+
+	_, err = batchUpdate(ctx, srv, spreadsheetId, requests)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
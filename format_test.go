@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 標準の書式では、土日・祝日の色がヘッダーの行に付かず、ヘッダーの色を上書きしない
+func TestApplyFormatShadingBelowHeader(t *testing.T) {
+	fake := newFakeSheets("2024年6月", 20, 32)
+	srv := fake.service(t)
+
+	spec, err := defaultFormatSpec(2024, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.Holidays = []int{3}
+	if err := ApplyFormat(context.Background(), srv, "fake", 1, spec); err != nil {
+		t.Fatalf("ApplyFormat: %v", err)
+	}
+
+	header, err := onSheet(spec.HeaderRange, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHeader := headerStyleRequest(header, spec.HeaderColor)
+
+	var shaded int
+	var headerFound bool
+	for _, request := range fake.requests {
+		repeat := request.RepeatCell
+		if repeat == nil {
+			continue
+		}
+		if repeat.Fields == wantHeader.RepeatCell.Fields {
+			headerFound = true
+			if !reflect.DeepEqual(repeat.Range, header) {
+				t.Errorf("header range = %+v, want %+v", repeat.Range, header)
+			}
+			continue
+		}
+		if repeat.Fields != "userEnteredFormat.backgroundColor" {
+			continue
+		}
+		shaded++
+		if repeat.Range.StartRowIndex < spec.HeaderRange.EndRowIndex {
+			t.Errorf("shading of column %d starts at row %d, inside the header", repeat.Range.StartColumnIndex, repeat.Range.StartRowIndex)
+		}
+	}
+	if !headerFound {
+		t.Error("header style request was not sent")
+	}
+	// 2024年6月の土日は10日、祝日として3日を加える
+	if shaded != 11 {
+		t.Errorf("shaded %d columns, want 11", shaded)
+	}
+}
+
+func TestNonWorkingDayRequestsWholeColumn(t *testing.T) {
+	requests, err := nonWorkingDayRequests(1, 2024, 6, 1, 0, nil, &sheets.Color{Red: 1}, []int{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	gr := requests[0].RepeatCell.Range
+	if gr.StartRowIndex != 0 || gr.EndRowIndex != 0 || gr.StartColumnIndex != 3 || gr.EndColumnIndex != 4 {
+		t.Errorf("range = %+v, want the whole column D", gr)
+	}
+}
+
+// FindReplace の返信がない場合は panic せずにエラーを返す
+func TestFindReplaceMissingReply(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+
+	if _, err := findReplace(context.Background(), srv, "fake", "早", "遅", nil, true, false, false); err == nil {
+		t.Error("findReplace succeeded without a FindReplace reply")
+	}
+}
+
+func TestReplacedOccurrences(t *testing.T) {
+	replies := []*sheets.Response{{FindReplace: &sheets.FindReplaceResponse{OccurrencesChanged: 3}}}
+	if got, err := replacedOccurrences(replies, 0); err != nil || got != 3 {
+		t.Errorf("replacedOccurrences = %d, %v; want 3", got, err)
+	}
+	for name, replies := range map[string][]*sheets.Response{
+		"no replies":  nil,
+		"nil reply":   {nil},
+		"other reply": {{AddSheet: &sheets.AddSheetResponse{}}},
+	} {
+		if _, err := replacedOccurrences(replies, 0); err == nil {
+			t.Errorf("%s: replacedOccurrences returned no error", name)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 勤務表の生成結果（-output json ではこの内容を出力する）
+type GenerateResult struct {
+	SpreadsheetId  string         `json:"spreadsheetId"`
+	SpreadsheetUrl string         `json:"spreadsheetUrl"`
+	Sheets         []sheetSummary `json:"sheets"`
+	Year           int            `json:"year"`
+	Month          int            `json:"month"`
+
+	// 作成したスプレッドシート（年月の入力前に取得したもの）
+	Spreadsheet *sheets.Spreadsheet `json:"-"`
+}
+
+// 作成したスプレッドシートと年月から生成結果を作成
+func newGenerateResult(spreadsheet *sheets.Spreadsheet, year int, month int) *GenerateResult {
+	result := &GenerateResult{
+		SpreadsheetId:  spreadsheet.SpreadsheetId,
+		SpreadsheetUrl: spreadsheet.SpreadsheetUrl,
+		Sheets:         []sheetSummary{},
+		Year:           year,
+		Month:          month,
+		Spreadsheet:    spreadsheet,
+	}
+	if result.SpreadsheetUrl == "" {
+		result.SpreadsheetUrl = spreadsheetURL(spreadsheet.SpreadsheetId)
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		result.Sheets = append(result.Sheets, sheetSummary{Title: sheet.Properties.Title, Id: sheet.Properties.SheetId})
+	}
+	return result
+}
+
+// テンプレートのシートをコピーして年月を入力した勤務表を作成し、生成結果を返す
+// template は getSpreadsheet で取得したテンプレートのスプレッドシート
+func CreateFromTemplate(ctx context.Context, srv *sheets.Service, template *sheets.Spreadsheet, year int, month int, locale string, timeZone string) (*GenerateResult, error) {
+	newSheet, err := createSpreadsheet(ctx, srv, nil, locale, timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spreadsheet: %w", err)
+	}
+
+	blankSheetId, err := createdBlankSheetId(newSheet)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = waitForSpreadsheet(ctx, srv, newSheet.SpreadsheetId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to access the new spreadsheet: %w", err)
+	}
+
+	// コピー先のID（作成したID）
+	destinationSpreadsheetId := newSheet.SpreadsheetId
+
+	err = copySpreadsheet(ctx, template, srv, template.SpreadsheetId, destinationSpreadsheetId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to copy template: %w", err)
+	}
+
+	// 空白シートの削除のレスポンスに削除後のスプレッドシートを含め、取得し直す往復を省く
+	// テンプレートにシートがない場合は最後の1枚になるため削除せず、年月の入力からも除く
+	var destinationSpreadsheet *sheets.Spreadsheet
+	var keptSheetIds []int64
+	if len(template.Sheets) > 0 {
+		destinationSpreadsheet, err = deleteBlankSheet(ctx, srv, blankSheetId, destinationSpreadsheetId)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		destinationSpreadsheet, err = getSpreadsheet(ctx, srv, destinationSpreadsheetId)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve sheets: %w", err)
+		}
+		keptSheetIds = append(keptSheetIds, blankSheetId)
+	}
+
+	// 年月の入力はためてから送信し、途中で中断された場合も送信を試みてから終了する
+	batch := newRequestBatch(srv, destinationSpreadsheetId)
+	defer flushOnInterrupt(ctx, batch, interruptFlushTimeout)()
+
+	requests, err := yearMonthRequests(destinationSpreadsheet, year, month, keptSheetIds...)
+	if err != nil {
+		return nil, err
+	}
+	batch.Add(requests...)
+	if _, err := batch.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("unable to update cells with year and month: %w", err)
+	}
+
+	err = stampGenerationMetadata(ctx, srv, destinationSpreadsheetId, year, month)
+	if err != nil {
+		return nil, fmt.Errorf("unable to stamp generation metadata: %w", err)
+	}
+
+	return newGenerateResult(destinationSpreadsheet, year, month), nil
+}
+
+// テンプレートを使わず、月の日数と社員数に合わせたサイズのシートを1枚持つ勤務表を作成し、生成結果を返す
+// シートは作成時に initialSheets として渡すため、空白シートの削除は不要
+// 年（A1）と月（A3）はテンプレートから作成した場合と同じセルに入力する
+func CreateSchedule(ctx context.Context, srv *sheets.Service, year int, month int, employees int, headerRows int, locale string, timeZone string) (*GenerateResult, error) {
+	if headerRows < 3 {
+		return nil, fmt.Errorf("headerRows must be at least 3 to hold the year (A1) and month (A3), got %d", headerRows)
+	}
+	sheet, err := scheduleSheet(fmt.Sprintf("%d年%d月", year, month), year, month, employees, headerRows)
+	if err != nil {
+		return nil, err
+	}
+
+	spreadsheet, err := createSpreadsheet(ctx, srv, []*sheets.Sheet{sheet}, locale, timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create spreadsheet: %w", err)
+	}
+
+	_, err = waitForSpreadsheet(ctx, srv, spreadsheet.SpreadsheetId)
+	if err != nil {
+		return nil, fmt.Errorf("unable to access the new spreadsheet: %w", err)
+	}
+
+	batch := newRequestBatch(srv, spreadsheet.SpreadsheetId)
+	defer flushOnInterrupt(ctx, batch, interruptFlushTimeout)()
+
+	requests, err := yearMonthRequests(spreadsheet, year, month)
+	if err != nil {
+		return nil, err
+	}
+	batch.Add(requests...)
+	if _, err := batch.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("unable to update cells with year and month: %w", err)
+	}
+
+	err = stampGenerationMetadata(ctx, srv, spreadsheet.SpreadsheetId, year, month)
+	if err != nil {
+		return nil, fmt.Errorf("unable to stamp generation metadata: %w", err)
+	}
+
+	return newGenerateResult(spreadsheet, year, month), nil
+}
+
+// 1か月分の勤務表を作成する関数（CreateFromTemplate か CreateSchedule を呼び出す）
+type generateFunc func(ctx context.Context, year int, month int) (*GenerateResult, error)
+
+// 1か月分の生成結果（失敗した場合は Err が設定される）
+type monthResult struct {
+	Month  int
+	Result *GenerateResult
+	Err    error
+}
+
+// 1年分の生成結果のまとめ
+type yearScheduleReport struct {
+	Year    int
+	Results []monthResult
+}
+
+// 失敗した月のエラーをまとめて返す（すべて成功した場合は nil）
+func (r *yearScheduleReport) Err() error {
+	var errs []error
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%d/%02d: %w", r.Year, result.Month, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// 月ごとの結果を出力
+func (r *yearScheduleReport) Print(w io.Writer) {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(w, "%d/%02d  failed: %v\n", r.Year, result.Month, result.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%d/%02d  %s\n", r.Year, result.Month, result.Result.SpreadsheetUrl)
+	}
+	fmt.Fprintf(w, "%d of %d months generated\n", len(r.Results)-failed, len(r.Results))
+}
+
+// 1年のうち months の月の勤務表を generate で最大 concurrency 件ずつ並行して作成する
+// 途中の月が失敗しても残りの月の作成は続け、月ごとの結果をまとめて返す
+func createYearSchedules(ctx context.Context, year int, months []int, concurrency int, generate generateFunc) (*yearScheduleReport, error) {
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", concurrency)
+	}
+	for _, month := range months {
+		if month < 1 || month > 12 {
+			return nil, fmt.Errorf("month %d is out of range 1-12", month)
+		}
+	}
+
+	report := &yearScheduleReport{Year: year, Results: make([]monthResult, len(months))}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, month := range months {
+		wg.Add(1)
+		go func(result *monthResult, month int) {
+			defer wg.Done()
+			result.Month = month
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				result.Err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+
+			debugf("generating %d/%02d", year, month)
+			result.Result, result.Err = generate(ctx, year, month)
+		}(&report.Results[i], month)
+	}
+	wg.Wait()
+
+	return report, nil
+}
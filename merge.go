@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 範囲のセルを結合（mergeType は MERGE_ALL / MERGE_COLUMNS / MERGE_ROWS）
+func mergeCells(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, gridRange *sheets.GridRange, mergeType string) error {
+	gr, err := onSheet(gridRange, sheetId)
+	if err != nil {
+		return err
+	}
+
+	mergeRequest := sheets.Request{
+		MergeCells: &sheets.MergeCellsRequest{
+			Range:     gr,
+			MergeType: mergeType,
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&mergeRequest},
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 範囲に含まれる結合セルをすべて解除
+// 以前の結合レイアウトの上に作り直すときは、書き込み前に解除しておく
+func unmergeCells(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, gridRange *sheets.GridRange) error {
+	gr, err := onSheet(gridRange, sheetId)
+	if err != nil {
+		return err
+	}
+
+	unmergeRequest := sheets.Request{
+		UnmergeCells: &sheets.UnmergeCellsRequest{
+			Range: gr,
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&unmergeRequest},
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// true の場合、書き込み先に重なる結合セルを書き込み前に解除する
+var unmergeBeforeWrite bool
+
+// 範囲の左上のセルから values を書き込んだときに、結合セルの左上以外へ書き込まないかを確認
+// 結合セルの値は左上のセルにしか表示されないため、それ以外に書き込む場合はエラーを返す
+// unmerge が true の場合はエラーにせず、重なる結合セルを解除する
+// target は書き込み先のシートで、結合セル（merges）を含めて取得しておくこと
+func checkMergedTarget(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string, r a1Range, target *sheets.Sheet, values [][]interface{}, unmerge bool) error {
+	columns := 0
+	for _, row := range values {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if len(values) == 0 || columns == 0 {
+		return nil
+	}
+	// 書き込まれるのは範囲の左上から values の大きさの分だけ
+	startRow, startColumn := r.StartRow, r.StartColumn
+	endRow, endColumn := startRow+int64(len(values)), startColumn+int64(columns)
+
+	var conflicts []*sheets.GridRange
+	for _, merge := range target.Merges {
+		top, bottom := max64(merge.StartRowIndex, startRow), min64(merge.EndRowIndex, endRow)
+		left, right := max64(merge.StartColumnIndex, startColumn), min64(merge.EndColumnIndex, endColumn)
+		if top >= bottom || left >= right {
+			continue
+		}
+		// 重なりが結合セルの左上の1セルだけなら問題ない
+		if top == merge.StartRowIndex && left == merge.StartColumnIndex && bottom == top+1 && right == left+1 {
+			continue
+		}
+		conflicts = append(conflicts, merge)
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+
+	mergeA1 := func(gr *sheets.GridRange) string {
+		return cellA1("", gr.StartRowIndex, gr.StartColumnIndex) + ":" + cellA1("", gr.EndRowIndex-1, gr.EndColumnIndex-1)
+	}
+	if !unmerge {
+		return fmt.Errorf("range %q writes into merged cells %s; write only to its top-left cell %s or unmerge it first",
+			a1, mergeA1(conflicts[0]), cellA1("", conflicts[0].StartRowIndex, conflicts[0].StartColumnIndex))
+	}
+
+	requests := make([]*sheets.Request, 0, len(conflicts))
+	for _, merge := range conflicts {
+		debugf("unmerging %s on sheet %q before writing %q", mergeA1(merge), target.Properties.Title, a1)
+		requests = append(requests, &sheets.Request{
+			UnmergeCells: &sheets.UnmergeCellsRequest{Range: merge},
+		})
+	}
+	_, err := batchUpdate(ctx, srv, spreadsheetId, requests)
+	return err
+}
+
+// 小さい方の値
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// 大きい方の値
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 結合・解除のリクエストには対象のシートのIDを設定し、呼び出し側の範囲は変更しない
+func TestMergeCellsKeepsCallerRange(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+	ctx := context.Background()
+
+	gridRange := &sheets.GridRange{SheetId: 99, StartRowIndex: 0, EndRowIndex: 2, StartColumnIndex: 0, EndColumnIndex: 3}
+	if err := mergeCells(ctx, srv, "fake", 1, gridRange, "MERGE_ALL"); err != nil {
+		t.Fatalf("mergeCells: %v", err)
+	}
+	if err := unmergeCells(ctx, srv, "fake", 1, gridRange); err != nil {
+		t.Fatalf("unmergeCells: %v", err)
+	}
+
+	if gridRange.SheetId != 99 {
+		t.Errorf("caller's range sheet ID = %d, want 99", gridRange.SheetId)
+	}
+	if len(fake.requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(fake.requests))
+	}
+	if got := fake.requests[0].MergeCells.Range.SheetId; got != 1 {
+		t.Errorf("merge range sheet ID = %d, want 1", got)
+	}
+	if got := fake.requests[1].UnmergeCells.Range.SheetId; got != 1 {
+		t.Errorf("unmerge range sheet ID = %d, want 1", got)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 生成したファイルに記録するメタデータのキーの名前空間
+const metadataKeyPrefix = "kinmuhyo-generator."
+
+// 生成ツールのバージョン
+const generatorVersion = "1.0.0"
+
+// スプレッドシート単位のメタデータを検索するフィルタ
+func spreadsheetMetadataFilter(key string) *sheets.DataFilter {
+	return &sheets.DataFilter{
+		DeveloperMetadataLookup: &sheets.DeveloperMetadataLookup{
+			MetadataKey:  metadataKeyPrefix + key,
+			LocationType: "SPREADSHEET",
+		},
+	}
+}
+
+// スプレッドシートに記録したメタデータを取得（見つからない場合は ok が false）
+func getDeveloperMetadata(ctx context.Context, srv *sheets.Service, spreadsheetId string, key string) (value string, ok bool, err error) {
+	searchRequest := &sheets.SearchDeveloperMetadataRequest{
+		DataFilters: []*sheets.DataFilter{spreadsheetMetadataFilter(key)},
+	}
+
+	resp, err := srv.Spreadsheets.DeveloperMetadata.Search(spreadsheetId, searchRequest).Context(ctx).Do()
+	if err != nil {
+		return "", false, err
+	}
+	if len(resp.MatchedDeveloperMetadata) == 0 {
+		return "", false, nil
+	}
+
+	return resp.MatchedDeveloperMetadata[0].DeveloperMetadata.MetadataValue, true, nil
+}
+
+// スプレッドシートにメタデータを記録（同じキーがあれば値を更新）
+func setDeveloperMetadata(ctx context.Context, srv *sheets.Service, spreadsheetId string, key string, value string) error {
+	_, exists, err := getDeveloperMetadata(ctx, srv, spreadsheetId, key)
+	if err != nil {
+		return err
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{developerMetadataRequest(key, value, exists)},
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// メタデータを記録するリクエストを作成（exists が true なら値の更新、false なら作成）
+func developerMetadataRequest(key string, value string, exists bool) *sheets.Request {
+	var request sheets.Request
+	if exists {
+		request.UpdateDeveloperMetadata = &sheets.UpdateDeveloperMetadataRequest{
+			DataFilters: []*sheets.DataFilter{spreadsheetMetadataFilter(key)},
+			DeveloperMetadata: &sheets.DeveloperMetadata{
+				MetadataValue: value,
+			},
+			Fields: "metadataValue",
+		}
+	} else {
+		request.CreateDeveloperMetadata = &sheets.CreateDeveloperMetadataRequest{
+			DeveloperMetadata: &sheets.DeveloperMetadata{
+				MetadataKey:   metadataKeyPrefix + key,
+				MetadataValue: value,
+				Location:      &sheets.DeveloperMetadataLocation{Spreadsheet: true},
+				Visibility:    "DOCUMENT",
+			},
+		}
+	}
+	return &request
+}
+
+// 生成した年月とツールのバージョンをスプレッドシートに記録
+// 既存のキーの検索を1回で行い、作成・更新のリクエストは1回のBatchUpdateでまとめて送信する
+func stampGenerationMetadata(ctx context.Context, srv *sheets.Service, spreadsheetId string, year int, month int) error {
+	stamps := []struct {
+		key   string
+		value string
+	}{
+		{"year", strconv.Itoa(year)},
+		{"month", strconv.Itoa(month)},
+		{"version", generatorVersion},
+	}
+
+	searchRequest := &sheets.SearchDeveloperMetadataRequest{}
+	for _, stamp := range stamps {
+		searchRequest.DataFilters = append(searchRequest.DataFilters, spreadsheetMetadataFilter(stamp.key))
+	}
+	resp, err := srv.Spreadsheets.DeveloperMetadata.Search(spreadsheetId, searchRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	exists := map[string]bool{}
+	for _, matched := range resp.MatchedDeveloperMetadata {
+		exists[matched.DeveloperMetadata.MetadataKey] = true
+	}
+
+	var requests []*sheets.Request
+	for _, stamp := range stamps {
+		requests = append(requests, developerMetadataRequest(stamp.key, stamp.value, exists[metadataKeyPrefix+stamp.key]))
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
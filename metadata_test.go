@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+// 記録は検索1回とBatchUpdate1回で行い、既存のキーは作成し直さずに値を更新する
+func TestStampGenerationMetadata(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+	ctx := context.Background()
+	fake.metadata[metadataKeyPrefix+"year"] = "2023"
+
+	if err := stampGenerationMetadata(ctx, srv, "fake", 2024, 4); err != nil {
+		t.Fatalf("stampGenerationMetadata: %v", err)
+	}
+
+	if fake.calls != 2 {
+		t.Errorf("made %d API calls, want 2", fake.calls)
+	}
+	if len(fake.requests) != 3 {
+		t.Fatalf("got %d requests, want 3", len(fake.requests))
+	}
+	if fake.requests[0].UpdateDeveloperMetadata == nil {
+		t.Error("existing year was not updated")
+	}
+	for _, request := range fake.requests[1:] {
+		if request.CreateDeveloperMetadata == nil {
+			t.Errorf("new key was not created: %+v", request)
+		}
+	}
+
+	for key, want := range map[string]string{"year": "2024", "month": "4", "version": generatorVersion} {
+		got, ok, err := getDeveloperMetadata(ctx, srv, "fake", key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || got != want {
+			t.Errorf("%s = %q (found %v), want %q", key, got, ok, want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API呼び出しごとに呼ばれるメトリクスのフック
+type callObserver interface {
+	ObserveCall(method string, dur time.Duration, err error)
+}
+
+// 呼び出し回数・エラー回数・所要時間の集計
+type callStats struct {
+	Calls  int
+	Errors int
+	Total  time.Duration
+}
+
+// メソッドごとに呼び出しを集計するメモリ上の実装
+type callCounter struct {
+	mu    sync.Mutex
+	stats map[string]*callStats
+}
+
+func newCallCounter() *callCounter {
+	return &callCounter{stats: map[string]*callStats{}}
+}
+
+func (c *callCounter) ObserveCall(method string, dur time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.stats[method]
+	if !ok {
+		s = &callStats{}
+		c.stats[method] = s
+	}
+	s.Calls++
+	s.Total += dur
+	if err != nil {
+		s.Errors++
+	}
+}
+
+// 集計結果をメソッド名順に出力
+func (c *callCounter) Print(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	methods := make([]string, 0, len(c.stats))
+	for method := range c.stats {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	var calls, errs int
+	for _, method := range methods {
+		s := c.stats[method]
+		fmt.Fprintf(w, "%-50s calls=%d errors=%d total=%s\n", method, s.Calls, s.Errors, s.Total.Round(time.Millisecond))
+		calls += s.Calls
+		errs += s.Errors
+	}
+	fmt.Fprintf(w, "total: %d API calls, %d errors\n", calls, errs)
+}
+
+// IDや範囲を含むURLのパスから "POST spreadsheets/{id}:batchUpdate" のようなメソッド名を作成
+func apiMethodName(req *http.Request) string {
+	path := req.URL.Path
+	for _, prefix := range []string{"/v4/", "/drive/v3/", "/upload/drive/v3/"} {
+		if strings.HasPrefix(path, prefix) {
+			path = strings.TrimPrefix(path, prefix)
+			break
+		}
+	}
+
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		switch segments[i-1] {
+		case "spreadsheets", "values", "sheets", "developerMetadata", "files", "permissions":
+			// ":batchUpdate" のような動詞は残す（範囲の "A1:A3" は動詞ではない）
+			verb := ""
+			if j := strings.LastIndex(segments[i], ":"); j >= 0 && isAPIVerb(segments[i][j+1:]) {
+				verb = segments[i][j:]
+			}
+			segments[i] = "{id}" + verb
+		}
+	}
+
+	return req.Method + " " + strings.Join(segments, "/")
+}
+
+// 小文字で始まる英字のみの文字列かどうか
+func isAPIVerb(s string) bool {
+	if s == "" || s[0] < 'a' || s[0] > 'z' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isASCIILetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// すべてのAPI呼び出しを callObserver に通知する RoundTripper
+type metricsTransport struct {
+	base     http.RoundTripper
+	observer callObserver
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	observed := err
+	if err == nil && resp.StatusCode >= 400 {
+		observed = fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	t.observer.ObserveCall(apiMethodName(req), time.Since(start), observed)
+
+	return resp, err
+}
+
+// http.Client の呼び出しを observer に通知するようにする
+func instrumentClient(client *http.Client, observer callObserver) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &metricsTransport{base: base, observer: observer}
+}
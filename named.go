@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// スプレッドシートの名前付き範囲をすべて取得（ない場合は空のスライス）
+// テンプレートの領域を A1 表記の決め打ちではなく名前で探すために使う
+func listNamedRanges(ctx context.Context, srv *sheets.Service, spreadsheetId string) ([]*sheets.NamedRange, error) {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("namedRanges").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	if spreadsheet.NamedRanges == nil {
+		return []*sheets.NamedRange{}, nil
+	}
+	return spreadsheet.NamedRanges, nil
+}
+
+// 名前付き範囲を名前から取得
+func findNamedRange(ctx context.Context, srv *sheets.Service, spreadsheetId string, name string) (*sheets.NamedRange, error) {
+	namedRanges, err := listNamedRanges(ctx, srv, spreadsheetId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, namedRange := range namedRanges {
+		if namedRange.Name == name {
+			return namedRange, nil
+		}
+	}
+
+	return nil, fmt.Errorf("named range %q does not exist", name)
+}
+
+// 名前付き範囲の直下に行を挿入して値を書き込み、名前付き範囲を書き込んだ行まで広げる
+// レイアウトが変わっても名前で追記先を特定できる（挿入・書き込み・範囲の更新は1回のBatchUpdateで行う）
+func appendToNamedRange(ctx context.Context, srv *sheets.Service, spreadsheetId string, name string, values [][]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	namedRange, err := findNamedRange(ctx, srv, spreadsheetId, name)
+	if err != nil {
+		return err
+	}
+	gr := namedRange.Range
+	if gr.EndRowIndex == 0 {
+		return fmt.Errorf("named range %q has no row bound, so there is no row below it to append to", name)
+	}
+
+	rows := make([]*sheets.RowData, 0, len(values))
+	for _, rowValues := range values {
+		if gr.EndColumnIndex != 0 && int64(len(rowValues)) > gr.EndColumnIndex-gr.StartColumnIndex {
+			return fmt.Errorf("row has %d values but named range %q is %d columns wide", len(rowValues), name, gr.EndColumnIndex-gr.StartColumnIndex)
+		}
+		cells := make([]*sheets.CellData, 0, len(rowValues))
+		for _, v := range rowValues {
+			ev, err := toExtendedValue(v)
+			if err != nil {
+				return err
+			}
+			cells = append(cells, &sheets.CellData{UserEnteredValue: ev})
+		}
+		rows = append(rows, &sheets.RowData{Values: cells})
+	}
+
+	newEnd := gr.EndRowIndex + int64(len(values))
+	extended := *gr
+	extended.EndRowIndex = newEnd
+
+	requests := []*sheets.Request{
+		{
+			// 範囲の下にある内容を押し下げ、書式は範囲の最終行から引き継ぐ
+			InsertDimension: &sheets.InsertDimensionRequest{
+				Range: &sheets.DimensionRange{
+					SheetId:    gr.SheetId,
+					Dimension:  "ROWS",
+					StartIndex: gr.EndRowIndex,
+					EndIndex:   newEnd,
+				},
+				InheritFromBefore: true,
+			},
+		},
+		{
+			UpdateCells: &sheets.UpdateCellsRequest{
+				Start: &sheets.GridCoordinate{
+					SheetId:     gr.SheetId,
+					RowIndex:    gr.EndRowIndex,
+					ColumnIndex: gr.StartColumnIndex,
+				},
+				Rows:   rows,
+				Fields: "userEnteredValue",
+			},
+		},
+		{
+			UpdateNamedRange: &sheets.UpdateNamedRangeRequest{
+				NamedRange: &sheets.NamedRange{
+					NamedRangeId: namedRange.NamedRangeId,
+					Range:        &extended,
+				},
+				Fields: "range",
+			},
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}
+
+	_, err = srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// 作成済みのスプレッドシートを記録する操作ログ（JSON Lines）の1行
+type operationLogEntry struct {
+	SpreadsheetId string    `json:"spreadsheetId"`
+	Year          int       `json:"year"`
+	Month         int       `json:"month"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// 操作ログを読み込み（ファイルがなければ空）
+func readOperationLog(path string) ([]operationLogEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []operationLogEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry operationLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// 指定した年月のスプレッドシートが作成済みかを探す
+func findOperation(entries []operationLogEntry, year int, month int) (operationLogEntry, bool) {
+	for _, entry := range entries {
+		if entry.Year == year && entry.Month == month {
+			return entry, true
+		}
+	}
+	return operationLogEntry{}, false
+}
+
+// 操作ログに1行追記
+func appendOperationLog(path string, entry operationLogEntry) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(entry)
+}
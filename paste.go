@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 範囲の行数と列数（境界なしの場合は0）
+func gridRangeSize(gr *sheets.GridRange) (rows int64, columns int64) {
+	if gr.EndRowIndex != 0 {
+		rows = gr.EndRowIndex - gr.StartRowIndex
+	}
+	if gr.EndColumnIndex != 0 {
+		columns = gr.EndColumnIndex - gr.StartColumnIndex
+	}
+	return rows, columns
+}
+
+// 範囲をコピーして別の範囲に貼り付け（pasteType は PASTE_NORMAL / PASTE_FORMAT など）
+// 書式の貼り付けでは、貼り付け先はコピー元の行数・列数の整数倍でなければならない
+func copyPaste(ctx context.Context, srv *sheets.Service, spreadsheetId string, sourceRange *sheets.GridRange, destRange *sheets.GridRange, pasteType string) error {
+	switch pasteType {
+	case "PASTE_NORMAL", "PASTE_VALUES", "PASTE_FORMAT", "PASTE_NO_BORDERS", "PASTE_FORMULA",
+		"PASTE_DATA_VALIDATION", "PASTE_CONDITIONAL_FORMATTING":
+	default:
+		return fmt.Errorf("invalid paste type %q", pasteType)
+	}
+	if err := validateGridRange(sourceRange); err != nil {
+		return fmt.Errorf("source range: %w", err)
+	}
+	if err := validateGridRange(destRange); err != nil {
+		return fmt.Errorf("destination range: %w", err)
+	}
+
+	if pasteType == "PASTE_FORMAT" {
+		srcRows, srcCols := gridRangeSize(sourceRange)
+		dstRows, dstCols := gridRangeSize(destRange)
+		if srcRows == 0 || srcCols == 0 || dstRows == 0 || dstCols == 0 {
+			return fmt.Errorf("source and destination ranges must be bounded for PASTE_FORMAT")
+		}
+		if dstRows%srcRows != 0 || dstCols%srcCols != 0 {
+			return fmt.Errorf("destination %dx%d is not a multiple of source %dx%d", dstRows, dstCols, srcRows, srcCols)
+		}
+	}
+
+	copyPasteRequest := sheets.Request{
+		CopyPaste: &sheets.CopyPasteRequest{
+			Source:           sourceRange,
+			Destination:      destRange,
+			PasteType:        pasteType,
+			PasteOrientation: "NORMAL",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&copyPasteRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 範囲を切り取り、destTopLeft を左上として貼り付ける（値・書式ごと移動）
+func cutPaste(ctx context.Context, srv *sheets.Service, spreadsheetId string, sourceRange *sheets.GridRange, destTopLeft *sheets.GridCoordinate) error {
+	if err := validateGridRange(sourceRange); err != nil {
+		return fmt.Errorf("source range: %w", err)
+	}
+	if destTopLeft == nil {
+		return fmt.Errorf("destination coordinate is required")
+	}
+	if destTopLeft.RowIndex < 0 || destTopLeft.ColumnIndex < 0 {
+		return fmt.Errorf("destination coordinate must not be negative")
+	}
+
+	cutPasteRequest := sheets.Request{
+		CutPaste: &sheets.CutPasteRequest{
+			Source:      sourceRange,
+			Destination: destTopLeft,
+			PasteType:   "PASTE_NORMAL",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&cutPasteRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
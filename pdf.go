@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// エラー時にレスポンスの本文から読み取る最大のバイト数
+const maxErrorBodyBytes = 512
+
+// PDF の余白の上限（インチ）
+const maxPDFMarginInches = 2
+
+// PDF の余白（インチ）
+type PDFMargins struct {
+	Top    float64
+	Bottom float64
+	Left   float64
+	Right  float64
+}
+
+// PDF 書き出しのレイアウト
+// Scale は 1: 標準（100%）、2: 幅に合わせる、3: 高さに合わせる、4: ページに合わせる（0 は指定なし）
+// FitWidth は Scale 2 と同じ意味の古い指定で、Scale の 1・3・4 とは同時に指定できない
+// Range は書き出す範囲（"A1:AG40" のようなシート名なしの A1 表記、空の場合はシート全体）
+type PDFOptions struct {
+	Landscape bool
+	FitWidth  bool
+	Gridlines bool
+	Margins   *PDFMargins
+	Scale     int
+	Range     string
+}
+
+// 月の勤務表を印刷するときの既定のレイアウト（縦向き・幅に合わせる・枠線あり）
+// 幅に合わせる指定は Scale で行うため、Scale を変えるだけで別の合わせ方にできる
+func defaultPDFOptions() PDFOptions {
+	return PDFOptions{Scale: 2, Gridlines: true}
+}
+
+// 設定値を検証し、エクスポート用URLのクエリパラメータに変換
+func (o PDFOptions) query() (url.Values, error) {
+	if o.Scale < 0 || o.Scale > 4 {
+		return nil, fmt.Errorf("pdf scale must be between 1 and 4 (or 0 to leave it unset), got %d", o.Scale)
+	}
+	if o.FitWidth && o.Scale != 0 && o.Scale != 2 {
+		return nil, fmt.Errorf("pdf scale %d conflicts with fit to width", o.Scale)
+	}
+
+	query := url.Values{}
+	query.Set("portrait", strconv.FormatBool(!o.Landscape))
+	query.Set("fitw", strconv.FormatBool(o.FitWidth))
+	query.Set("gridlines", strconv.FormatBool(o.Gridlines))
+	if o.Scale != 0 {
+		query.Set("scale", strconv.Itoa(o.Scale))
+	}
+	if o.Range != "" {
+		if err := validatePrintArea(o.Range); err != nil {
+			return nil, err
+		}
+		query.Set("range", o.Range)
+	}
+	if m := o.Margins; m != nil {
+		for _, margin := range []struct {
+			name  string
+			value float64
+		}{{"top", m.Top}, {"bottom", m.Bottom}, {"left", m.Left}, {"right", m.Right}} {
+			if margin.value < 0 || margin.value > maxPDFMarginInches {
+				return nil, fmt.Errorf("pdf %s margin %v is out of range [0, %d] inches", margin.name, margin.value, maxPDFMarginInches)
+			}
+			query.Set(margin.name+"_margin", strconv.FormatFloat(margin.value, 'f', -1, 64))
+		}
+	}
+	return query, nil
+}
+
+// シートを opts のレイアウトで PDF として書き出す
+// Sheets API には PDF の出力がないため、認証済みの httpClient でスプレッドシートのエクスポート用URLを取得する
+func exportSheetPDF(ctx context.Context, httpClient *http.Client, spreadsheetId string, gid int64, opts PDFOptions, w io.Writer) error {
+	query, err := opts.query()
+	if err != nil {
+		return err
+	}
+	query.Set("format", "pdf")
+	query.Set("gid", strconv.FormatInt(gid, 10))
+	exportURL := "https://docs.google.com/spreadsheets/d/" + url.PathEscape(spreadsheetId) + "/export?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, exportURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return fmt.Errorf("unable to export sheet %d of %s as pdf: %s: %s", gid, spreadsheetId, resp.Status, strings.TrimSpace(string(body)))
+	}
+	// 権限がない場合などはログイン画面の HTML が 200 で返ることがある
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/pdf") {
+		return fmt.Errorf("unable to export sheet %d of %s as pdf: unexpected content type %q", gid, spreadsheetId, contentType)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// 印刷範囲として記録するメタデータのキー（シートごと）
+func printAreaKey(sheetId int64) string {
+	return "printArea." + strconv.FormatInt(sheetId, 10)
+}
+
+// 印刷範囲が "A1:AG40" のようなシート名なしの閉じた範囲かを検証
+func validatePrintArea(a1 string) error {
+	r, err := parseA1Range(a1)
+	if err != nil {
+		return err
+	}
+	if r.Sheet != "" || !r.hasRows || !r.hasColumns || r.openEndRow {
+		return fmt.Errorf("print area %q must be a cell range without a sheet name, such as A1:AG40", a1)
+	}
+	return nil
+}
+
+// シートの印刷範囲を記録
+// Sheets API には印刷範囲の設定がないため、デベロッパーメタデータに記録して exportPrintAreaPDF で書き出すときに使う
+// スプレッドシートの画面からの印刷には反映されない
+// 改ページも API・エクスポートのどちらでも指定できないため、1か月を1ページに収めるには
+// defaultPDFOptions の Scale を 4（ページに合わせる）に変えて書き出す
+func setPrintArea(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, a1 string) error {
+	if err := validatePrintArea(a1); err != nil {
+		return err
+	}
+	return setDeveloperMetadata(ctx, srv, spreadsheetId, printAreaKey(sheetId), a1)
+}
+
+// 記録したシートの印刷範囲を取得（記録がない場合は ok が false）
+func getPrintArea(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64) (a1 string, ok bool, err error) {
+	return getDeveloperMetadata(ctx, srv, spreadsheetId, printAreaKey(sheetId))
+}
+
+// シートを記録した印刷範囲で PDF として書き出す（opts.Range の指定が優先、記録がなければシート全体）
+func exportPrintAreaPDF(ctx context.Context, httpClient *http.Client, srv *sheets.Service, spreadsheetId string, gid int64, opts PDFOptions, w io.Writer) error {
+	if opts.Range == "" {
+		a1, ok, err := getPrintArea(ctx, srv, spreadsheetId, gid)
+		if err != nil {
+			return err
+		}
+		if ok {
+			opts.Range = a1
+		}
+	}
+	return exportSheetPDF(ctx, httpClient, spreadsheetId, gid, opts, w)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPDFOptionsQuery(t *testing.T) {
+	onePage := defaultPDFOptions()
+	onePage.Scale = 4
+
+	tests := []struct {
+		name      string
+		opts      PDFOptions
+		wantQuery map[string]string
+		wantErr   string
+	}{
+		{name: "default", opts: defaultPDFOptions(), wantQuery: map[string]string{"scale": "2", "fitw": "false", "portrait": "true", "gridlines": "true"}},
+		{name: "default fit to page", opts: onePage, wantQuery: map[string]string{"scale": "4"}},
+		{name: "unset scale", opts: PDFOptions{}, wantQuery: map[string]string{"scale": ""}},
+		{name: "fit width with scale 2", opts: PDFOptions{FitWidth: true, Scale: 2}, wantQuery: map[string]string{"fitw": "true", "scale": "2"}},
+		{name: "fit width with fit to page", opts: PDFOptions{FitWidth: true, Scale: 4}, wantErr: "conflicts with fit to width"},
+		{name: "scale too large", opts: PDFOptions{Scale: 5}, wantErr: "between 1 and 4 (or 0 to leave it unset), got 5"},
+		{name: "negative scale", opts: PDFOptions{Scale: -1}, wantErr: "got -1"},
+	}
+	for _, tt := range tests {
+		query, err := tt.opts.query()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		for key, want := range tt.wantQuery {
+			if got := query.Get(key); got != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, key, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// シート全体を保護し、unprotectedRanges だけを編集可能にして、保護範囲のIDを返す
+// 配布する勤務表でシフトのセルだけを編集させたい場合に使う
+func protectSheetExcept(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, unprotectedRanges []*sheets.GridRange) (int64, error) {
+	ranges := make([]*sheets.GridRange, len(unprotectedRanges))
+	for i, unprotected := range unprotectedRanges {
+		gr, err := onSheet(unprotected, sheetId)
+		if err != nil {
+			return 0, err
+		}
+		ranges[i] = gr
+	}
+
+	addProtectedRangeRequest := sheets.Request{
+		AddProtectedRange: &sheets.AddProtectedRangeRequest{
+			ProtectedRange: &sheets.ProtectedRange{
+				Range:             &sheets.GridRange{SheetId: sheetId},
+				UnprotectedRanges: ranges,
+				Description:       "編集可能なセル以外を保護",
+			},
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&addProtectedRangeRequest},
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	return addedProtectedRangeId(resp.Replies, 0)
+}
+
+// 範囲を保護し、保護範囲のIDを返す
+// warningOnly が false の場合は編集を禁止し、editors（メールアドレス）を省略すると認証しているユーザーだけが編集できる
+// warningOnly が true の場合は編集時に警告を表示するだけになり、広く共有している勤務表でも誰でも編集できる
+// API の制約で警告のみの保護には編集者を指定できないため、両方を指定した場合はエラーを返す
+func protectRange(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange, description string, editors []string, warningOnly bool) (int64, error) {
+	if err := validateGridRange(gridRange); err != nil {
+		return 0, err
+	}
+	if warningOnly && len(editors) > 0 {
+		return 0, fmt.Errorf("editors cannot be set on a warning-only protected range")
+	}
+
+	protectedRange := &sheets.ProtectedRange{
+		Range:       gridRange,
+		Description: description,
+		WarningOnly: warningOnly,
+	}
+	if len(editors) > 0 {
+		protectedRange.Editors = &sheets.Editors{Users: editors}
+	}
+
+	addProtectedRangeRequest := sheets.Request{
+		AddProtectedRange: &sheets.AddProtectedRangeRequest{
+			ProtectedRange: protectedRange,
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&addProtectedRangeRequest},
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	return addedProtectedRangeId(resp.Replies, 0)
+}
+
+// 先頭の rows 行と cols 列を固定し、あわせて保護して保護範囲のIDを返す（1回のBatchUpdateで行う）
+// 保護範囲は1つの範囲しか持てないため、シート全体を保護して固定範囲以外を編集可能にする
+func lockHeaders(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, rows int64, cols int64) (int64, error) {
+	if rows < 0 || cols < 0 {
+		return 0, fmt.Errorf("rows and cols must not be negative, got %d and %d", rows, cols)
+	}
+	if rows == 0 && cols == 0 {
+		return 0, fmt.Errorf("at least one of rows and cols must be positive")
+	}
+
+	freezeRequest := sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId: sheetId,
+				GridProperties: &sheets.GridProperties{
+					FrozenRowCount:    rows,
+					FrozenColumnCount: cols,
+					ForceSendFields:   []string{"FrozenRowCount", "FrozenColumnCount"},
+				},
+			},
+			Fields: "gridProperties.frozenRowCount,gridProperties.frozenColumnCount",
+		},
+	}
+
+	addProtectedRangeRequest := sheets.Request{
+		AddProtectedRange: &sheets.AddProtectedRangeRequest{
+			ProtectedRange: &sheets.ProtectedRange{
+				Range: &sheets.GridRange{SheetId: sheetId},
+				UnprotectedRanges: []*sheets.GridRange{
+					{SheetId: sheetId, StartRowIndex: rows, StartColumnIndex: cols},
+				},
+				Description: "見出しを保護",
+			},
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&freezeRequest, &addProtectedRangeRequest},
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	return addedProtectedRangeId(resp.Replies, 1)
+}
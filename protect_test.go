@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 編集可能な範囲には対象のシートのIDを設定し、呼び出し側の範囲は変更しない
+func TestProtectSheetExceptKeepsCallerRanges(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+
+	unprotected := []*sheets.GridRange{
+		{SheetId: 99, StartRowIndex: 3, StartColumnIndex: 1},
+		{SheetId: 98, StartRowIndex: 0, EndRowIndex: 1},
+	}
+	if _, err := protectSheetExcept(context.Background(), srv, "fake", 1, unprotected); err != nil {
+		t.Fatalf("protectSheetExcept: %v", err)
+	}
+
+	if unprotected[0].SheetId != 99 || unprotected[1].SheetId != 98 {
+		t.Errorf("caller's ranges were changed: %+v, %+v", unprotected[0], unprotected[1])
+	}
+	for i, gr := range fake.requests[0].AddProtectedRange.ProtectedRange.UnprotectedRanges {
+		if gr.SheetId != 1 {
+			t.Errorf("unprotected range %d sheet ID = %d, want 1", i, gr.SheetId)
+		}
+	}
+}
@@ -3,44 +3,83 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
+// プロファイルに対応するトークンファイルのパスを返す
+// プロファイル未指定なら token.json、指定があれば token-<profile>.json
+func tokenFilePath(profile string) (string, error) {
+	if profile == "" {
+		return "token.json", nil
+	}
+	if strings.ContainsAny(profile, `/\`) || profile == "." || profile == ".." {
+		return "", fmt.Errorf("invalid profile name %q", profile)
+	}
+	return "token-" + profile + ".json", nil
+}
+
 // トークンを取得して保存し、生成されたクライアントを返す
-func getClient(config *oauth2.Config) *http.Client {
-	// token.json ファイルは、ユーザーのアクセスとリフレッシュトークンを保存するファイルで、認証フローが初めて完了したときに自動的に作成
-	tokFile := "token.json"
+// authPort が0以上の場合はローカルのリダイレクト先（redirectHost のホスト）で認証コードを受け取り、負の場合は手入力してもらう
+func getClient(ctx context.Context, config *oauth2.Config, tokFile string, redirectHost string, authPort int) *http.Client {
+	// トークンファイルは、ユーザーのアクセスとリフレッシュトークンを保存するファイルで、認証フローが初めて完了したときに自動的に作成
 	tok, err := tokenFromFile(tokFile)
 	if err != nil {
-		tok = getTokenFromWeb(config)
+		if errors.Is(err, os.ErrNotExist) {
+			infof("No token found at %s; starting authorization for this profile.\n", tokFile)
+		} else {
+			infof("Unable to read token from %s (%v); starting authorization again.\n", tokFile, err)
+		}
+		if authPort >= 0 {
+			tok = getTokenFromLocalServer(ctx, config, redirectHost, authPort)
+		} else {
+			tok = getTokenFromWeb(config)
+		}
 		saveToken(tokFile, tok)
 	}
 	return config.Client(context.Background(), tok)
 }
 
 // Webからトークンを要求し、取得したトークンを返す
+// 認証コードの代わりにリダイレクト先のURL全体を貼り付けた場合は、state も検証する
 func getTokenFromWeb(config *oauth2.Config) *oauth2.Token {
+	session, err := newAuthSession()
+	if err != nil {
+		log.Fatalf("Unable to start authorization: %v", err)
+	}
+
 	// 認証コードを取得するためのURLを作成
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser then type the "+
-		"authorization code: \n%v\n", authURL)
+	authURL := session.authCodeURL(config)
+	printAuthURL("Go to the following link in your browser then type the authorization code:", authURL)
 
 	var authCode string
 	if _, err := fmt.Scan(&authCode); err != nil {
 		log.Fatalf("Unable to read authorization code: %v", err)
 	}
+	if u, err := url.Parse(authCode); err == nil && u.Query().Get("code") != "" {
+		if err := session.verifyState(u.Query().Get("state")); err != nil {
+			log.Fatalf("Unable to retrieve token from web: %v", err)
+		}
+		authCode = u.Query().Get("code")
+	}
 
-	tok, err := config.Exchange(context.TODO(), authCode)
+	tok, err := session.exchange(context.TODO(), config, authCode)
 	if err != nil {
 		log.Fatalf("Unable to retrieve token from web: %v", err)
 	}
@@ -61,7 +100,7 @@ func tokenFromFile(file string) (*oauth2.Token, error) {
 
 // トークンをファイルパスに保存
 func saveToken(path string, token *oauth2.Token) {
-	fmt.Printf("Saving credential file to: %s\n", path)
+	infof("Saving credential file to: %s\n", path)
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		log.Fatalf("Unable to cache oauth token: %v", err)
@@ -71,14 +110,27 @@ func saveToken(path string, token *oauth2.Token) {
 }
 
 // スプレッドシートの新規作成
-func createSpreadsheet(srv *sheets.Service) (*sheets.Spreadsheet, error) {
+// initialSheets を指定すると最初からそのシートを持った状態で作成され、空白シートは作られない
+// （nil の場合は空白シートが1枚作られるので、テンプレートをコピーした後に削除する）
+// 500 などの一時的なエラーはリトライするが、作成済みでレスポンスだけ失われた場合は
+// APIに冪等キーがないため重複して作成される可能性がある（ベストエフォート）
+// locale と timeZone は作成時のプロパティとして設定し、最初の書き込みから日付の表示を正しくする
+func createSpreadsheet(ctx context.Context, srv *sheets.Service, initialSheets []*sheets.Sheet, locale string, timeZone string) (*sheets.Spreadsheet, error) {
 	spreadsheet := &sheets.Spreadsheet{
 		Properties: &sheets.SpreadsheetProperties{
-			Title: "勤務表作成テスト",
+			Title:    "勤務表作成テスト",
+			Locale:   locale,
+			TimeZone: timeZone,
 		},
+		Sheets: initialSheets,
 	}
 
-	newSheet, err := srv.Spreadsheets.Create(spreadsheet).Do()
+	var newSheet *sheets.Spreadsheet
+	err := withRetry(ctx, func() error {
+		var err error
+		newSheet, err = srv.Spreadsheets.Create(spreadsheet).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -86,9 +138,37 @@ func createSpreadsheet(srv *sheets.Service) (*sheets.Spreadsheet, error) {
 	return newSheet, nil
 }
 
+// スプレッドシートを開くURL
+func spreadsheetURL(spreadsheetId string) string {
+	return "https://docs.google.com/spreadsheets/d/" + spreadsheetId + "/edit"
+}
+
+// スプレッドシートの特定のシートを開くURL
+func sheetURL(spreadsheetId string, sheetId int64) string {
+	return spreadsheetURL(spreadsheetId) + "#gid=" + strconv.FormatInt(sheetId, 10)
+}
+
 // スプレッドシートをシートIDから取得
-func getSpreadsheet(srv *sheets.Service, spreadsheetId string) (*sheets.Spreadsheet, error) {
-	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Do()
+func getSpreadsheet(ctx context.Context, srv *sheets.Service, spreadsheetId string) (*sheets.Spreadsheet, error) {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return spreadsheet, nil
+}
+
+// 作成直後のスプレッドシートが取得できるようになるまで待機
+// 作成直後は反映の遅れで 404 が返ることがあるため、404 と一時的なエラーをバックオフしながらリトライする
+func waitForSpreadsheet(ctx context.Context, srv *sheets.Service, spreadsheetId string) (*sheets.Spreadsheet, error) {
+	var spreadsheet *sheets.Spreadsheet
+	err := defaultRetryConfig.doWhen(ctx, func(err error) bool {
+		return isNotFound(err) || isRetryable(err)
+	}, func() error {
+		var err error
+		spreadsheet, err = srv.Spreadsheets.Get(spreadsheetId).Context(ctx).Do()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -96,147 +176,488 @@ func getSpreadsheet(srv *sheets.Service, spreadsheetId string) (*sheets.Spreadsh
 	return spreadsheet, nil
 }
 
+// コピーしたシート名から「のコピー」や "Copy of" を取り除く
+// 何度もコピーされて "X のコピー のコピー" のようになった場合も、目印がなくなるまで繰り返し取り除く
+func stripCopyMarkers(title string) string {
+	for {
+		trimmed := strings.TrimSpace(title)
+		trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, "のコピー"))
+		trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "Copy of "))
+		if trimmed == title || trimmed == "" {
+			return title
+		}
+		title = trimmed
+	}
+}
+
+// コピー先のスプレッドシートのセル数が上限（1,000万セル）を超える場合のエラー
+var ErrCellLimitExceeded = errors.New("spreadsheet cell limit exceeded")
+
+// セル数の上限を超えたことによる 400 エラーかどうかを判定
+func isCellLimitExceeded(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		return false
+	}
+	msg := strings.ToLower(apiErr.Message)
+	return strings.Contains(msg, "above the limit") && strings.Contains(msg, "cells")
+}
+
 // IDで指定したスプレッドシートをコピー
 func copySpreadsheet(ctx context.Context, sourceSpreadsheet *sheets.Spreadsheet, srv *sheets.Service, sourceSpreadsheetId string, destinationSpreadsheetId string) error {
+	renames := map[int64]string{}
 	for _, sheet := range sourceSpreadsheet.Sheets {
 		rb := &sheets.CopySheetToAnotherSpreadsheetRequest{
 			DestinationSpreadsheetId: destinationSpreadsheetId,
 		}
 
 		resp, err := srv.Spreadsheets.Sheets.CopyTo(sourceSpreadsheetId, sheet.Properties.SheetId, rb).Context(ctx).Do()
-		if err != nil {
-			log.Fatal(err)
+		if isCellLimitExceeded(err) {
+			return fmt.Errorf("%w: copying sheet %q would exceed the 10,000,000 cell limit; "+
+				"delete unused empty rows and columns from the template or split it into separate spreadsheets: %v",
+				ErrCellLimitExceeded, sheet.Properties.Title, err)
 		}
-
-		newSheetTitle := strings.TrimSuffix(resp.Title, "のコピー")
-
-		updateSheetNameRequest := sheets.Request{
-			UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
-				Properties: &sheets.SheetProperties{
-					SheetId: resp.SheetId,
-					Title:   newSheetTitle,
-				},
-				Fields: "title",
-			},
+		if err != nil {
+			return err
 		}
 
-		batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
-			Requests: []*sheets.Request{&updateSheetNameRequest},
-		}
+		renames[resp.SheetId] = stripCopyMarkers(resp.Title)
+	}
 
-		_, err = srv.Spreadsheets.BatchUpdate(destinationSpreadsheetId, batchUpdateRequest).Context(ctx).Do()
-		if err != nil {
-			log.Fatalf("Unable to update sheet name: %v", err)
-		}
+	// コピー後のシート名の変更はまとめて1回で行う
+	err := renameSheets(ctx, srv, destinationSpreadsheetId, renames)
+	if err != nil {
+		return fmt.Errorf("unable to update sheet name: %w", err)
 	}
 
 	return nil
 }
 
-// 空白のスプレッドシートを削除
-func deleteBlankSheet(ctx context.Context, srv *sheets.Service, newSheet *sheets.Spreadsheet, destinationSpreadsheetId string) error {
-	blankSheetId := newSheet.Sheets[0].Properties.SheetId
+// 作成直後のスプレッドシートから自動作成された空白シートのIDを取得
+// コピー後はシートの並びが変わるため、作成時に取得したIDを保持して削除に使う
+// 空白シートには削除まで何も書き込まないため、削除の前に空かどうかは確認しない
+func createdBlankSheetId(newSheet *sheets.Spreadsheet) (int64, error) {
+	if len(newSheet.Sheets) != 1 {
+		return 0, fmt.Errorf("expected exactly one blank sheet in the new spreadsheet, found %d", len(newSheet.Sheets))
+	}
+	return newSheet.Sheets[0].Properties.SheetId, nil
+}
 
+// 空白のスプレッドシートを削除し、削除後のスプレッドシートを返す
+func deleteBlankSheet(ctx context.Context, srv *sheets.Service, blankSheetId int64, destinationSpreadsheetId string) (*sheets.Spreadsheet, error) {
 	deleteSheetRequest := sheets.Request{
 		DeleteSheet: &sheets.DeleteSheetRequest{
 			SheetId: blankSheetId,
 		},
 	}
 
-	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
-		Requests: []*sheets.Request{&deleteSheetRequest},
+	spreadsheet, err := batchUpdateWithSpreadsheet(ctx, srv, destinationSpreadsheetId, []*sheets.Request{&deleteSheetRequest}, nil, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to delete sheet: %w", err)
+	}
+
+	return spreadsheet, nil
+}
+
+// スプレッドシートのタイムゾーン設定を取得（未設定の場合はローカルのタイムゾーン）
+func spreadsheetLocation(spreadsheet *sheets.Spreadsheet) (*time.Location, error) {
+	if spreadsheet.Properties == nil || spreadsheet.Properties.TimeZone == "" {
+		return time.Local, nil
 	}
+	return time.LoadLocation(spreadsheet.Properties.TimeZone)
+}
 
-	_, err := srv.Spreadsheets.BatchUpdate(destinationSpreadsheetId, batchUpdateRequest).Context(ctx).Do()
+// 指定したタイムゾーンでの現在の年と月
+func currentYearMonth(loc *time.Location) (int, int) {
+	now := time.Now().In(loc)
+	return now.Year(), int(now.Month())
+}
+
+// -timeout の既定値（1か月分の勤務表の作成にかかる時間の目安）
+const defaultTimeout = 2 * time.Minute
+
+// 全体の期限を返す（-timeout を指定しなかった場合は、作成する月数に合わせて既定値を延ばす）
+func runTimeout(timeout time.Duration, set bool, months int) time.Duration {
+	if set || months <= 1 {
+		return timeout
+	}
+	return timeout * time.Duration(months)
+}
+
+// セルA1とA3に年と月を入力
+// 年月は loc のタイムゾーンで判定する（nil の場合はスプレッドシートのタイムゾーン）
+func updateCellsYearMonth(ctx context.Context, srv *sheets.Service, destinationSpreadsheet *sheets.Spreadsheet, destinationSpreadsheetId string, loc *time.Location) error {
+	if loc == nil {
+		var err error
+		loc, err = spreadsheetLocation(destinationSpreadsheet)
+		if err != nil {
+			return err
+		}
+	}
+	year, month := currentYearMonth(loc)
+
+	return writeYearMonth(ctx, srv, destinationSpreadsheet, destinationSpreadsheetId, year, month)
+}
+
+// 全シートのセルA1とA3に指定した年と月を入力
+// 値と表示形式を同じリクエストで設定し、書式なしの値が一瞬表示されるのを防ぐ
+func writeYearMonth(ctx context.Context, srv *sheets.Service, destinationSpreadsheet *sheets.Spreadsheet, destinationSpreadsheetId string, year int, month int) error {
+	requests, err := yearMonthRequests(destinationSpreadsheet, year, month)
 	if err != nil {
-		log.Fatalf("Unable to delete sheet: %v", err)
+		return err
+	}
+
+	_, err = batchUpdate(ctx, srv, destinationSpreadsheetId, requests)
+	if err != nil {
+		return fmt.Errorf("unable to update cells with year and month: %w", err)
 	}
 
 	return nil
 }
 
-// セルA1とA3に年と月を入力
-func updateCellsYearMonth(ctx context.Context, srv *sheets.Service, destinationSpreadsheet *sheets.Spreadsheet, destinationSpreadsheetId string) error {
-	now := time.Now()
-	year := now.Year()
-	month := int(now.Month())
+// 全シートのセルA1とA3に年と月を入力するリクエストを作成（skipSheetIds のシートは除く）
+func yearMonthRequests(destinationSpreadsheet *sheets.Spreadsheet, year int, month int, skipSheetIds ...int64) ([]*sheets.Request, error) {
+	// 年が "2,026" のように区切られないよう整数の形式を指定
+	numberFormat := &sheets.NumberFormat{Type: "NUMBER", Pattern: "0"}
+
+	skip := map[int64]bool{}
+	for _, sheetId := range skipSheetIds {
+		skip[sheetId] = true
+	}
 
+	var requests []*sheets.Request
 	for _, sheet := range destinationSpreadsheet.Sheets {
-		sheetName := sheet.Properties.Title
-		values := [][]interface{}{
-			{year},
-			{},
-			{month},
+		sheetId := sheet.Properties.SheetId
+		if skip[sheetId] {
+			continue
 		}
 
-		updateValuesRequest := &sheets.ValueRange{
-			Range:          sheetName + "!A1:A3",
-			Values:         values,
-			MajorDimension: "ROWS",
+		yearRequest, err := updateCellsWithFormatRequest(sheetId, 0, 0, [][]interface{}{{year}}, numberFormat)
+		if err != nil {
+			return nil, err
 		}
-
-		_, err := srv.Spreadsheets.Values.Update(destinationSpreadsheetId, updateValuesRequest.Range, updateValuesRequest).ValueInputOption("RAW").Context(ctx).Do()
+		monthRequest, err := updateCellsWithFormatRequest(sheetId, 2, 0, [][]interface{}{{month}}, numberFormat)
 		if err != nil {
-			log.Fatalf("Unable to update cells with year and month: %v", err)
+			return nil, err
 		}
+		requests = append(requests, yearRequest, monthRequest)
 	}
 
-	return nil
+	return requests, nil
 }
 
 func main() {
+	profile := flag.String("profile", "", "OAuth profile name; uses token-<profile>.json instead of token.json")
+	reauth := flag.Bool("reauth", false, "delete the stale token file when the refresh token has been revoked or lacks a required scope")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the cell-by-cell changes writeRange would make instead of writing")
+	flag.StringVar(&valueInputOption, "value-input", "RAW", "how written values are interpreted: RAW or USER_ENTERED")
+	flag.BoolVar(&unmergeBeforeWrite, "unmerge", false, "unmerge merged cells that a write would overlap instead of failing")
+	flag.BoolVar(&r1c1Ranges, "r1c1", false, "treat ranges as R1C1 notation (e.g. R1C1:R3C[2]) and convert them to A1")
+	flag.StringVar(&defaultSheetName, "sheet", "", "sheet name used for ranges that omit one")
+	flag.BoolVar(&debug, "debug", false, "print debug logs")
+	flag.BoolVar(&quiet, "quiet", false, "send credential messages to the debug log instead of stdout; the authorization URL is still printed to stderr")
+	shareWith := flag.String("share", "", "comma-separated email addresses to share the generated spreadsheet with as writers")
+	notify := flag.Bool("notify", false, "send a notification email when sharing")
+	printResult := flag.Bool("print", false, "print the first visible sheet of the generated spreadsheet as a table")
+	output := flag.String("output", "", `set to "json" to print the created spreadsheet's id, url, sheets, year and month as JSON`)
+	showMetrics := flag.Bool("metrics", false, "print per-method API call counts at the end of the run")
+	opLogPath := flag.String("oplog", "", "JSON lines file recording generated spreadsheets; months already recorded are skipped")
+	force := flag.Bool("force", false, "generate even if the operation log already records this month")
+	authPort := flag.Int("auth-port", -1, "receive the OAuth redirect on this local port (0 picks a free port); negative means paste the code manually")
+	locale := flag.String("locale", "ja_JP", "locale of the created spreadsheet")
+	timeZone := flag.String("timezone", "Asia/Tokyo", "time zone of the created spreadsheet, also used to determine the current month")
+	readA1 := flag.String("read", "", "print this A1 range of -spreadsheet instead of generating a new spreadsheet")
+	readSpreadsheetId := flag.String("spreadsheet", "", "spreadsheet ID to read with -read")
+	readFormat := flag.String("format", "table", "output format for -read: table, csv, json or markdown")
+	readOutput := flag.String("read-output", "", "file to write the -read output to instead of stdout")
+	timeout := flag.Duration("timeout", defaultTimeout, "abort the run if it takes longer than this after authorization; 0 disables the limit; unless set, -year allows this much per month")
+	scheduleYear := flag.Int("year", 0, "generate all 12 months of this year instead of only the current month")
+	concurrency := flag.Int("concurrency", 3, "maximum months generated at the same time with -year")
+	employees := flag.Int("employees", 0, "create a sheet sized to the month's days + 2 columns and this many employee rows instead of copying the template")
+	headerRows := flag.Int("header-rows", 4, "header rows above the employee rows with -employees (at least 3, for the year in A1 and month in A3)")
+	flag.IntVar(&defaultRetryConfig.MaxAttempts, "max-retries", defaultRetryConfig.MaxAttempts, "maximum attempts for API calls that fail transiently")
+	flag.DurationVar(&defaultRetryConfig.BaseDelay, "backoff", defaultRetryConfig.BaseDelay, "initial delay between retries; doubles after each attempt")
+	flag.DurationVar(&defaultRetryConfig.MaxDelay, "max-backoff", defaultRetryConfig.MaxDelay, "upper bound on the delay between retries")
+	flag.Parse()
+	timeoutSet := false
+	flag.Visit(func(f *flag.Flag) {
+		timeoutSet = timeoutSet || f.Name == "timeout"
+	})
+
+	if err := validateValueInputOption(valueInputOption); err != nil {
+		log.Fatalf("Invalid -value-input: %v", err)
+	}
+	if *timeout < 0 {
+		log.Fatalf("Invalid -timeout %s: must not be negative", *timeout)
+	}
+	if err := defaultRetryConfig.validate(); err != nil {
+		log.Fatalf("Invalid retry settings: %v", err)
+	}
+	loc, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		log.Fatalf("Invalid -timezone: %v", err)
+	}
+	if *output != "" && *output != "json" {
+		log.Fatalf("Invalid -output %q: must be json", *output)
+	}
+	switch *readFormat {
+	case "table", "csv", "json", "markdown":
+	default:
+		log.Fatalf("Invalid -format %q: must be table, csv, json or markdown", *readFormat)
+	}
+	if *readA1 != "" && *readSpreadsheetId == "" {
+		log.Fatalf("-read requires -spreadsheet")
+	}
+	if *employees < 0 {
+		log.Fatalf("Invalid -employees %d: must not be negative", *employees)
+	}
+	if *headerRows < 3 {
+		log.Fatalf("Invalid -header-rows %d: must be at least 3", *headerRows)
+	}
+	if *concurrency <= 0 {
+		log.Fatalf("Invalid -concurrency %d: must be positive", *concurrency)
+	}
+	if *scheduleYear != 0 && (*shareWith != "" || *printResult || *output != "") {
+		log.Fatalf("-year cannot be combined with -share, -print or -output")
+	}
+
 	ctx := context.Background()
-	b, err := os.ReadFile("credentials.json")
+	b, err := readCredentials()
 	if err != nil {
-		log.Fatalf("Unable to ReaFile: %v", err)
+		log.Fatalf("Unable to read credentials: %v", err)
 	}
 
-	config, err := google.ConfigFromJSON(b, "https://www.googleapis.com/auth/spreadsheets")
+	config, err := google.ConfigFromJSON(b, "https://www.googleapis.com/auth/spreadsheets", drive.DriveFileScope)
 	if err != nil {
 		log.Fatalf("Unable to ConfigFromJSON: %v", err)
 	}
-	client := getClient(config)
-
-	srv, err := sheets.NewService(ctx, option.WithHTTPClient(client))
+	tokFile, err := tokenFilePath(*profile)
 	if err != nil {
-		log.Fatalf("Unable to NewService: %v", err)
+		log.Fatalf("Unable to use profile: %v", err)
 	}
+	var host string
+	if *authPort >= 0 {
+		registered, err := registeredRedirectURIs(b)
+		if err != nil {
+			log.Fatalf("Unable to read redirect URIs from credentials: %v", err)
+		}
+		host, err = redirectHost(registered, *authPort)
+		if err != nil {
+			log.Fatalf("Invalid -auth-port: %v", err)
+		}
+	}
+	client := getClient(ctx, config, tokFile, host, *authPort)
 
-	newSheet, err := createSpreadsheet(srv)
-	if err != nil {
-		log.Fatalf("Unable to createSpreadsheet: %v", err)
+	// 認証の後は Ctrl-C で API 呼び出しを中断し、ためたリクエストを送信してから終了する
+	ctx, stopInterrupt := interruptContext(ctx)
+	defer stopInterrupt()
+
+	// 認証の待ち時間は含めず、API呼び出しの全体に期限を設ける
+	if *scheduleYear != 0 {
+		*timeout = runTimeout(*timeout, timeoutSet, 12)
+	}
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
 	}
 
-	// コピー元のID
-	sourceSpreadsheetId := ""
+	var counter *callCounter
+	if *showMetrics {
+		counter = newCallCounter()
+		instrumentClient(client, counter)
+	}
 
-	// コピー先のID（作成したID）
-	destinationSpreadsheetId := newSheet.SpreadsheetId
+	// API呼び出しの失敗で終了する（リフレッシュトークンの失効時は再認証を案内）
+	fail := func(msg string, err error) {
+		if isInvalidGrant(err) {
+			reportInvalidGrant(tokFile, *reauth)
+		}
+		if isInsufficientScope(err) {
+			reportInsufficientScope(tokFile, *reauth)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Fatalf("%s: the run did not finish within -timeout %s: %v", msg, *timeout, err)
+		}
+		if errors.Is(err, context.Canceled) {
+			log.Printf("%s: interrupted: %v", msg, err)
+			if counter != nil {
+				counter.Print(os.Stderr)
+			}
+			os.Exit(130)
+		}
+		log.Fatalf("%s: %v", msg, err)
+	}
 
-	sourceSpreadsheet, err := getSpreadsheet(srv, sourceSpreadsheetId)
+	srv, err := newSheetsService(ctx, client)
 	if err != nil {
-		log.Fatalf("Unable to Get source spreadsheet: %v", err)
+		fail("Unable to NewService", err)
 	}
 
-	err = copySpreadsheet(ctx, sourceSpreadsheet, srv, sourceSpreadsheetId, destinationSpreadsheetId)
-	if err != nil {
-		log.Fatalf("Unable to copySpreadsheet: %v", err)
+	if *readA1 != "" {
+		values, err := readRange(ctx, srv, *readSpreadsheetId, *readA1)
+		if err != nil {
+			fail("Unable to read range", err)
+		}
+
+		// 読み取りに失敗した場合に空のファイルを残さないよう、読み取った後に作成する
+		var f *os.File
+		w := io.Writer(os.Stdout)
+		if *readOutput != "" {
+			f, err = os.Create(*readOutput)
+			if err != nil {
+				log.Fatalf("Unable to create read output file: %v", err)
+			}
+			w = f
+		}
+		if err := renderValues(w, values, *readFormat); err != nil {
+			log.Fatalf("Unable to write range: %v", err)
+		}
+		if f != nil {
+			if err := f.Close(); err != nil {
+				log.Fatalf("Unable to write read output file: %v", err)
+			}
+		}
+		if counter != nil {
+			counter.Print(os.Stderr)
+		}
+		return
+	}
+
+	// -employees の場合はテンプレートを使わず、サイズを指定したシートで作成する
+	generate := func(ctx context.Context, year int, month int) (*GenerateResult, error) {
+		return CreateSchedule(ctx, srv, year, month, *employees, *headerRows, *locale, *timeZone)
+	}
+	if *employees == 0 {
+		// コピー元のID
+		sourceSpreadsheetId := ""
+
+		problems, warnings, err := validateTemplate(ctx, srv, sourceSpreadsheetId)
+		if err != nil {
+			fail("Unable to validate template", err)
+		}
+		for _, warning := range warnings {
+			log.Printf("warning: template %q: %s", sourceSpreadsheetId, warning)
+		}
+		if len(problems) > 0 {
+			log.Fatalf("Template %q is not usable:\n  - %s", sourceSpreadsheetId, strings.Join(problems, "\n  - "))
+		}
+
+		sourceSpreadsheet, err := getSpreadsheet(ctx, srv, sourceSpreadsheetId)
+		if err != nil {
+			fail("Unable to Get source spreadsheet", err)
+		}
+		generate = func(ctx context.Context, year int, month int) (*GenerateResult, error) {
+			return CreateFromTemplate(ctx, srv, sourceSpreadsheet, year, month, *locale, *timeZone)
+		}
 	}
 
-	if len(sourceSpreadsheet.Sheets) > 0 {
-		err = deleteBlankSheet(ctx, srv, newSheet, destinationSpreadsheetId)
+	if *scheduleYear != 0 {
+		// 操作ログに記録済みの月は -force を指定しない限り作成しない（中断後の再実行で重複させない）
+		var entries []operationLogEntry
+		if *opLogPath != "" && !*force {
+			entries, err = readOperationLog(*opLogPath)
+			if err != nil {
+				log.Fatalf("Unable to read operation log: %v", err)
+			}
+		}
+		var months []int
+		for month := 1; month <= 12; month++ {
+			if done, ok := findOperation(entries, *scheduleYear, month); ok {
+				fmt.Printf("%d/%02d was already generated as %s; skipping (use -force to regenerate)\n", *scheduleYear, month, spreadsheetURL(done.SpreadsheetId))
+				continue
+			}
+			months = append(months, month)
+		}
+		if len(months) == 0 {
+			return
+		}
+
+		report, err := createYearSchedules(ctx, *scheduleYear, months, *concurrency, generate)
 		if err != nil {
-			log.Fatalf("Unable to delete blank sheet: %v", err)
+			fail("Unable to generate schedules", err)
 		}
+		report.Print(os.Stdout)
+		if *opLogPath != "" {
+			for _, result := range report.Results {
+				if result.Err != nil {
+					continue
+				}
+				entry := operationLogEntry{SpreadsheetId: result.Result.SpreadsheetId, Year: *scheduleYear, Month: result.Month, CreatedAt: time.Now()}
+				if err := appendOperationLog(*opLogPath, entry); err != nil {
+					log.Fatalf("Unable to record operation log: %v", err)
+				}
+			}
+		}
+		if err := report.Err(); err != nil {
+			fail("Unable to generate some months", err)
+		}
+		return
 	}
 
-	destinationSpreadsheet, err := getSpreadsheet(srv, destinationSpreadsheetId)
-	if err != nil {
-		log.Fatalf("Unable to retrieve sheets: %v", err)
+	// 年月はサーバーではなく作成するスプレッドシートのタイムゾーンで判定する
+	year, month := currentYearMonth(loc)
+
+	if *opLogPath != "" && !*force {
+		entries, err := readOperationLog(*opLogPath)
+		if err != nil {
+			log.Fatalf("Unable to read operation log: %v", err)
+		}
+		if done, ok := findOperation(entries, year, month); ok {
+			fmt.Printf("%d/%02d was already generated as %s; skipping (use -force to regenerate)\n", year, month, spreadsheetURL(done.SpreadsheetId))
+			return
+		}
 	}
 
-	err = updateCellsYearMonth(ctx, srv, destinationSpreadsheet, destinationSpreadsheetId)
+	result, err := generate(ctx, year, month)
 	if err != nil {
-		log.Fatalf("Unable to update cells with year and month: %v", err)
+		fail("Unable to generate spreadsheet", err)
+	}
+	destinationSpreadsheetId := result.SpreadsheetId
+
+	if *opLogPath != "" {
+		entry := operationLogEntry{SpreadsheetId: destinationSpreadsheetId, Year: year, Month: month, CreatedAt: time.Now()}
+		if err := appendOperationLog(*opLogPath, entry); err != nil {
+			log.Fatalf("Unable to record operation log: %v", err)
+		}
+	}
+
+	if first := visibleSheet(result.Spreadsheet); *printResult && first != nil {
+		sheetName := first.Properties.Title
+		valueRange, err := srv.Spreadsheets.Values.Get(destinationSpreadsheetId, quoteSheetName(sheetName)).Context(ctx).Do()
+		if err != nil {
+			fail("Unable to read generated sheet", err)
+		}
+
+		fmt.Println(sheetName)
+		if err := renderTable(os.Stdout, valueRange.Values); err != nil {
+			log.Fatalf("Unable to print generated sheet: %v", err)
+		}
+	}
+
+	if *shareWith != "" {
+		driveSrv, err := drive.NewService(ctx, option.WithHTTPClient(client))
+		if err != nil {
+			fail("Unable to create Drive service", err)
+		}
+
+		for _, email := range strings.Split(*shareWith, ",") {
+			_, err = shareSpreadsheet(ctx, driveSrv, destinationSpreadsheetId, strings.TrimSpace(email), "writer", *notify)
+			if err != nil {
+				fail("Unable to share spreadsheet with "+email, err)
+			}
+		}
+	}
+
+	if *output == "json" {
+		if err := writeGenerateResultJSON(os.Stdout, result); err != nil {
+			log.Fatalf("Unable to write JSON output: %v", err)
+		}
+	} else {
+		fmt.Printf("Created spreadsheet: %s\n", result.SpreadsheetUrl)
+	}
+
+	if counter != nil {
+		counter.Print(os.Stderr)
 	}
 }
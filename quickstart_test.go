@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestStripCopyMarkers(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{title: "勤務表", want: "勤務表"},
+		{title: "勤務表 のコピー", want: "勤務表"},
+		{title: "勤務表 のコピー のコピー", want: "勤務表"},
+		{title: "勤務表のコピー", want: "勤務表"},
+		{title: "Copy of Schedule", want: "Schedule"},
+		{title: "Copy of Copy of Schedule", want: "Schedule"},
+		{title: "Copy of 勤務表 のコピー", want: "勤務表"},
+		{title: "Copy of Copy of 勤務表 のコピー のコピー", want: "勤務表"},
+		{title: "  勤務表 のコピー  ", want: "勤務表"},
+		// 名前の途中の目印は残す
+		{title: "のコピー用紙", want: "のコピー用紙"},
+		{title: "Schedule (Copy of 2023)", want: "Schedule (Copy of 2023)"},
+		// 目印だけの名前は空にせず、最後の目印を残す
+		{title: "のコピー", want: "のコピー"},
+		{title: "のコピー のコピー", want: "のコピー"},
+		{title: "Copy of Copy of", want: "Copy of"},
+	}
+	for _, tt := range tests {
+		if got := stripCopyMarkers(tt.title); got != tt.want {
+			t.Errorf("stripCopyMarkers(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestYearMonthRequestsSkipsSheets(t *testing.T) {
+	spreadsheet := &sheets.Spreadsheet{Sheets: []*sheets.Sheet{
+		{Properties: &sheets.SheetProperties{SheetId: 0, Title: "シート1"}},
+		{Properties: &sheets.SheetProperties{SheetId: 7, Title: "勤務表"}},
+	}}
+
+	requests, err := yearMonthRequests(spreadsheet, 2024, 4, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want year and month for one sheet", len(requests))
+	}
+	for _, request := range requests {
+		if id := request.UpdateCells.Start.SheetId; id != 7 {
+			t.Errorf("request targets sheet %d, want 7", id)
+		}
+	}
+
+	all, err := yearMonthRequests(spreadsheet, 2024, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Errorf("got %d requests without skipping, want 4", len(all))
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		set     bool
+		months  int
+		want    time.Duration
+	}{
+		{name: "one month", timeout: defaultTimeout, months: 1, want: defaultTimeout},
+		{name: "default scaled for a year", timeout: defaultTimeout, months: 12, want: 24 * time.Minute},
+		{name: "explicit value kept for a year", timeout: 5 * time.Minute, set: true, months: 12, want: 5 * time.Minute},
+		{name: "explicitly disabled", timeout: 0, set: true, months: 12, want: 0},
+	}
+	for _, tt := range tests {
+		if got := runTimeout(tt.timeout, tt.set, tt.months); got != tt.want {
+			t.Errorf("%s: runTimeout(%s, %v, %d) = %s, want %s", tt.name, tt.timeout, tt.set, tt.months, got, tt.want)
+		}
+	}
+}
+
+// -timeout の期限が来ると、応答しないサーバーへの送信中のリクエストも中断して DeadlineExceeded を返す
+func TestTimeoutAbortsInFlightRequests(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	srv, err := sheets.NewService(context.Background(), option.WithHTTPClient(server.Client()), option.WithEndpoint(server.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helpers := map[string]func(ctx context.Context) error{
+		"getSpreadsheet": func(ctx context.Context) error {
+			_, err := getSpreadsheet(ctx, srv, "fake")
+			return err
+		},
+		"waitForSpreadsheet": func(ctx context.Context) error {
+			_, err := waitForSpreadsheet(ctx, srv, "fake")
+			return err
+		},
+		"readRange": func(ctx context.Context) error {
+			_, err := readRange(ctx, srv, "fake", "Sheet1!A1:B2")
+			return err
+		},
+		"batchUpdateChunked": func(ctx context.Context) error {
+			_, err := batchUpdateChunked(ctx, srv, "fake", yearMonthTestRequests(t), 1)
+			return err
+		},
+	}
+	for name, call := range helpers {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := call(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("error = %v, want context.DeadlineExceeded", err)
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("returned after %s, want shortly after the 50ms deadline", elapsed)
+			}
+		})
+	}
+}
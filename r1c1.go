@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// R1C1 表記のセル参照（"R3C2"、"R[-1]C[2]"、"RC[1]" など）
+var r1c1Pattern = regexp.MustCompile(`^[Rr](\d+|\[-?\d+\])?[Cc](\d+|\[-?\d+\])?$`)
+
+// A1 表記のセル参照（"B3"、"$B$3" など）
+var a1CellPattern = regexp.MustCompile(`^(\$?)([A-Za-z]+)(\$?)(\d+)$`)
+
+// 範囲を "!" と ":" で分け、セル参照ごとに conv で変換して組み立て直す
+func convertRangeRefs(r string, conv func(string) (string, error)) (string, error) {
+	prefix, cells := "", r
+	if i := strings.LastIndex(r, "!"); i >= 0 {
+		prefix, cells = r[:i+1], r[i+1:]
+	}
+	refs := strings.Split(cells, ":")
+	for i, ref := range refs {
+		converted, err := conv(ref)
+		if err != nil {
+			return "", fmt.Errorf("range %q: %v", r, err)
+		}
+		refs[i] = converted
+	}
+	return prefix + strings.Join(refs, ":"), nil
+}
+
+// R1C1 の行・列の部分を0始まりのインデックスに変換（"3" は絶対、"[-1]" と "" は base からの相対）
+func r1c1Index(part string, base int64) (index int64, absolute bool, err error) {
+	switch {
+	case part == "":
+		index = base
+	case strings.HasPrefix(part, "["):
+		offset, err := strconv.ParseInt(strings.Trim(part, "[]"), 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+		index = base + offset
+	default:
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+		index, absolute = n-1, true
+	}
+	if index < 0 {
+		return 0, false, fmt.Errorf("reference %q is before the first row or column", part)
+	}
+	return index, absolute, nil
+}
+
+// R1C1 表記の範囲を A1 表記に変換（相対参照は0始まりの baseRow, baseCol のセルを起点とする）
+// 絶対参照は "$B$3"、相対参照は "B3" のように $ の有無で区別する
+func r1c1ToA1(r1c1 string, baseRow int64, baseCol int64) (string, error) {
+	return convertRangeRefs(r1c1, func(ref string) (string, error) {
+		m := r1c1Pattern.FindStringSubmatch(ref)
+		if m == nil {
+			return "", fmt.Errorf("invalid R1C1 reference %q", ref)
+		}
+		row, rowAbsolute, err := r1c1Index(m[1], baseRow)
+		if err != nil {
+			return "", err
+		}
+		col, colAbsolute, err := r1c1Index(m[2], baseCol)
+		if err != nil {
+			return "", err
+		}
+
+		var b strings.Builder
+		if colAbsolute {
+			b.WriteString("$")
+		}
+		b.WriteString(columnLetters(col))
+		if rowAbsolute {
+			b.WriteString("$")
+		}
+		b.WriteString(strconv.FormatInt(row+1, 10))
+		return b.String(), nil
+	})
+}
+
+// A1 表記の範囲を R1C1 表記に変換（相対参照は0始まりの baseRow, baseCol のセルからの差にする）
+// "$" の付いた行・列は絶対参照、付いていないものは相対参照として扱う
+func a1ToR1C1(a1 string, baseRow int64, baseCol int64) (string, error) {
+	return convertRangeRefs(a1, func(ref string) (string, error) {
+		m := a1CellPattern.FindStringSubmatch(ref)
+		if m == nil {
+			return "", fmt.Errorf("invalid A1 cell reference %q", ref)
+		}
+		col, colAbsolute := columnIndex(m[2]), m[1] == "$"
+		row, err := strconv.ParseInt(m[4], 10, 64)
+		if err != nil || row < 1 {
+			return "", fmt.Errorf("invalid row in cell reference %q", ref)
+		}
+		row--
+		rowAbsolute := m[3] == "$"
+
+		part := func(prefix string, index int64, absolute bool, base int64) string {
+			switch {
+			case absolute:
+				return prefix + strconv.FormatInt(index+1, 10)
+			case index == base:
+				return prefix
+			default:
+				return prefix + "[" + strconv.FormatInt(index-base, 10) + "]"
+			}
+		}
+		return part("R", row, rowAbsolute, baseRow) + part("C", col, colAbsolute, baseCol), nil
+	})
+}
+
+// true の場合、範囲を R1C1 表記として受け取り A1 表記に変換する
+// "RC1" や "RC10" は A1 表記でも RC 列のセルとして有効なため、表記の推測はせず明示的に切り替える
+var r1c1Ranges bool
+
+// R1C1 表記の範囲を A1 表記に変換（相対参照は A1 を起点とする）
+func rangeToA1(r string) (string, error) {
+	return r1c1ToA1(r, 0, 0)
+}
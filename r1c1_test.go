@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+func TestR1C1ToA1(t *testing.T) {
+	tests := []struct {
+		name    string
+		r1c1    string
+		row     int64
+		col     int64
+		want    string
+		wantErr bool
+	}{
+		{name: "absolute cell", r1c1: "R3C2", want: "$B$3"},
+		{name: "absolute range with sheet", r1c1: "Sheet1!R1C1:R10C26", want: "Sheet1!$A$1:$Z$10"},
+		{name: "lowercase", r1c1: "r2c28", want: "$AB$2"},
+		{name: "relative from origin", r1c1: "R[1]C[2]", want: "C2"},
+		{name: "relative from base", r1c1: "R[-1]C[-1]", row: 4, col: 3, want: "C4"},
+		{name: "current row and column", r1c1: "RC", row: 2, col: 1, want: "B3"},
+		{name: "mixed", r1c1: "R1C[1]:R[2]C5", row: 1, col: 1, want: "C$1:$E4"},
+		{name: "column RC range from A1", r1c1: "Sheet1!RC1:RC10", want: "Sheet1!$A1:$J1"},
+		{name: "before first row", r1c1: "R[-1]C1", wantErr: true},
+		{name: "zero row", r1c1: "R0C1", wantErr: true},
+		{name: "A1 reference", r1c1: "B3", wantErr: true},
+		{name: "partly A1", r1c1: "R1C1:B3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r1c1ToA1(tt.r1c1, tt.row, tt.col)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("r1c1ToA1(%q) = %q, want error", tt.r1c1, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("r1c1ToA1(%q): %v", tt.r1c1, err)
+			}
+			if got != tt.want {
+				t.Errorf("r1c1ToA1(%q) = %q, want %q", tt.r1c1, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestA1ToR1C1(t *testing.T) {
+	tests := []struct {
+		a1   string
+		row  int64
+		col  int64
+		want string
+	}{
+		{a1: "$B$3", want: "R3C2"},
+		{a1: "Sheet1!$A$1:$Z$10", want: "Sheet1!R1C1:R10C26"},
+		{a1: "C2", want: "R[1]C[2]"},
+		{a1: "C4", row: 4, col: 3, want: "R[-1]C[-1]"},
+		{a1: "B3", row: 2, col: 1, want: "RC"},
+		{a1: "C$1:$E4", row: 1, col: 1, want: "R1C[1]:R[2]C5"},
+	}
+	for _, tt := range tests {
+		got, err := a1ToR1C1(tt.a1, tt.row, tt.col)
+		if err != nil {
+			t.Fatalf("a1ToR1C1(%q): %v", tt.a1, err)
+		}
+		if got != tt.want {
+			t.Errorf("a1ToR1C1(%q) = %q, want %q", tt.a1, got, tt.want)
+		}
+		back, err := r1c1ToA1(got, tt.row, tt.col)
+		if err != nil || back != tt.a1 {
+			t.Errorf("r1c1ToA1(%q) = %q, %v; want %q", got, back, err, tt.a1)
+		}
+	}
+}
+
+// RC 列などの A1 表記は -r1c1 を指定しない限りそのまま使う
+func TestQualifyRangeR1C1OptIn(t *testing.T) {
+	defer func(r1c1 bool, sheet string) { r1c1Ranges, defaultSheetName = r1c1, sheet }(r1c1Ranges, defaultSheetName)
+	defaultSheetName = "Sheet1"
+
+	tests := []struct {
+		r1c1    bool
+		a1      string
+		want    string
+		wantErr bool
+	}{
+		{r1c1: false, a1: "Sheet1!RC1:RC10", want: "Sheet1!RC1:RC10"},
+		{r1c1: false, a1: "rc5", want: "'Sheet1'!rc5"},
+		{r1c1: false, a1: "R1C1", want: "'Sheet1'!R1C1"},
+		{r1c1: true, a1: "Sheet1!RC1:RC10", want: "Sheet1!$A1:$J1"},
+		{r1c1: true, a1: "R2C3", want: "'Sheet1'!$C$2"},
+		{r1c1: true, a1: "B3", wantErr: true},
+	}
+	for _, tt := range tests {
+		r1c1Ranges = tt.r1c1
+		got, err := qualifyRange(tt.a1)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("qualifyRange(%q) with r1c1=%v = %q, want error", tt.a1, tt.r1c1, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("qualifyRange(%q) with r1c1=%v: %v", tt.a1, tt.r1c1, err)
+		}
+		if got != tt.want {
+			t.Errorf("qualifyRange(%q) with r1c1=%v = %q, want %q", tt.a1, tt.r1c1, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// A1表記の範囲を解析した結果
+// 行・列は0始まりの半開区間 [Start, End) で保持し、"A:C" や "2:5" のように省略された端は has* で表す
+type a1Range struct {
+	Sheet       string
+	StartRow    int64
+	EndRow      int64
+	StartColumn int64
+	EndColumn   int64
+	hasRows     bool
+	hasColumns  bool
+	openEndRow  bool
+}
+
+// A1表記の範囲を解析
+// "Sheet!A1:C3"、"A1"、"A:C"、"2:5"、"A2:C" のような半開範囲を受け付ける
+func parseA1Range(a1 string) (a1Range, error) {
+	var r a1Range
+	cells := a1
+	if i := strings.LastIndex(a1, "!"); i >= 0 {
+		r.Sheet = unquoteSheetName(a1[:i])
+		cells = a1[i+1:]
+	}
+	if cells == "" {
+		return r, fmt.Errorf("range %q has no cell reference", a1)
+	}
+
+	startRef, endRef, isPair := strings.Cut(cells, ":")
+	startCol, startRow, err := parseCellRef(startRef)
+	if err != nil {
+		return r, fmt.Errorf("range %q: %v", a1, err)
+	}
+	endCol, endRow := startCol, startRow
+	if isPair {
+		endCol, endRow, err = parseCellRef(endRef)
+		if err != nil {
+			return r, fmt.Errorf("range %q: %v", a1, err)
+		}
+	}
+
+	// 列・行の指定有無は開始側で決まる（"A2:C" は終端の行が開いている）
+	r.hasColumns = startCol >= 0
+	r.hasRows = startRow >= 0
+	if !r.hasColumns && !r.hasRows {
+		return r, fmt.Errorf("range %q has no cell reference", a1)
+	}
+	if r.hasColumns {
+		if endCol < 0 {
+			return r, fmt.Errorf("range %q: end of range is missing a column", a1)
+		}
+		r.StartColumn, r.EndColumn = startCol, endCol+1
+	} else if endCol >= 0 {
+		return r, fmt.Errorf("range %q: start of range is missing a column", a1)
+	}
+	if r.hasRows {
+		if endRow < 0 {
+			r.openEndRow = true
+			r.StartRow = startRow
+		} else {
+			r.StartRow, r.EndRow = startRow, endRow+1
+		}
+	} else if endRow >= 0 {
+		return r, fmt.Errorf("range %q: start of range is missing a row", a1)
+	}
+	if r.hasColumns && r.EndColumn <= r.StartColumn {
+		return r, fmt.Errorf("range %q: end column is before start column", a1)
+	}
+	if r.hasRows && !r.openEndRow && r.EndRow <= r.StartRow {
+		return r, fmt.Errorf("range %q: end row is before start row", a1)
+	}
+
+	return r, nil
+}
+
+// "B3" のようなセル参照を0始まりの列・行に分解（省略された側は -1）
+func parseCellRef(ref string) (col int64, row int64, err error) {
+	ref = strings.ReplaceAll(ref, "$", "")
+	i := 0
+	for i < len(ref) && isASCIILetter(ref[i]) {
+		i++
+	}
+	letters, digits := ref[:i], ref[i:]
+	if letters == "" && digits == "" {
+		return -1, -1, fmt.Errorf("empty cell reference")
+	}
+
+	col, row = -1, -1
+	if letters != "" {
+		col = columnIndex(letters)
+	}
+	if digits != "" {
+		n, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil || n < 1 {
+			return -1, -1, fmt.Errorf("invalid row in cell reference %q", ref)
+		}
+		row = n - 1
+	}
+	return col, row, nil
+}
+
+func isASCIILetter(c byte) bool {
+	return ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z')
+}
+
+// 列名（"A"、"AB" など）を0始まりのインデックスに変換
+func columnIndex(letters string) int64 {
+	var n int64
+	for _, c := range strings.ToUpper(letters) {
+		n = n*26 + int64(c-'A'+1)
+	}
+	return n - 1
+}
+
+// 0始まりのインデックスを列名に変換
+func columnLetters(index int64) string {
+	var b []byte
+	for n := index + 1; n > 0; n = (n - 1) / 26 {
+		b = append([]byte{byte('A' + (n-1)%26)}, b...)
+	}
+	return string(b)
+}
+
+// シート名を囲むシングルクォートを外す
+func unquoteSheetName(name string) string {
+	if len(name) >= 2 && name[0] == '\'' && name[len(name)-1] == '\'' {
+		return strings.ReplaceAll(name[1:len(name)-1], "''", "'")
+	}
+	return name
+}
+
+// シート名を A1 表記で使えるようにシングルクォートで囲む
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+// 0始まりの行・列から "'Sheet'!B3" のような A1 表記を作成（シート名が空なら "B3"）
+func cellA1(sheetName string, row int64, col int64) string {
+	ref := columnLetters(col) + strconv.FormatInt(row+1, 10)
+	if sheetName == "" {
+		return ref
+	}
+	return quoteSheetName(sheetName) + "!" + ref
+}
+
+// GridRange を "'Sheet'!B3:D10" のような A1 表記に変換
+// 終端が省略（0）されている場合はシートの行数・列数までとし、終端を補った範囲も返す
+func gridRangeA1(props *sheets.SheetProperties, gr *sheets.GridRange) (string, *sheets.GridRange, error) {
+	if err := validateGridRange(gr); err != nil {
+		return "", nil, err
+	}
+	if props.GridProperties == nil {
+		return "", nil, fmt.Errorf("sheet %q is not a grid sheet", props.Title)
+	}
+
+	resolved := *gr
+	resolved.SheetId = props.SheetId
+	if resolved.EndRowIndex == 0 {
+		resolved.EndRowIndex = props.GridProperties.RowCount
+	}
+	if resolved.EndColumnIndex == 0 {
+		resolved.EndColumnIndex = props.GridProperties.ColumnCount
+	}
+	if resolved.StartRowIndex >= resolved.EndRowIndex || resolved.StartColumnIndex >= resolved.EndColumnIndex {
+		return "", nil, fmt.Errorf("grid range is outside sheet %q", props.Title)
+	}
+
+	a1 := cellA1(props.Title, resolved.StartRowIndex, resolved.StartColumnIndex) + ":" +
+		cellA1("", resolved.EndRowIndex-1, resolved.EndColumnIndex-1)
+	return a1, &resolved, nil
+}
+
+// 非表示のシートを飛ばして先頭の表示されているシートを返す（すべて非表示なら nil）
+func visibleSheet(spreadsheet *sheets.Spreadsheet) *sheets.Sheet {
+	for _, sheet := range spreadsheet.Sheets {
+		if !sheet.Properties.Hidden {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// 先頭の表示されているシートを取得
+// シート名を省略した範囲はAPIでもこのシートが対象になるため、Sheets[0]（非表示の計算用シートの場合がある）ではなくこれを既定にする
+func firstVisibleSheet(ctx context.Context, srv *sheets.Service, spreadsheetId string) (*sheets.Sheet, error) {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := visibleSheet(spreadsheet)
+	if sheet == nil {
+		return nil, fmt.Errorf("spreadsheet %s has no visible sheets", spreadsheetId)
+	}
+	return sheet, nil
+}
+
+// シートのプロパティをタイトルから取得（タイトルが空の場合は先頭の表示されているシート）
+func getSheetProperties(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetName string) (*sheets.SheetProperties, error) {
+	if sheetName == "" {
+		sheet, err := firstVisibleSheet(ctx, srv, spreadsheetId)
+		if err != nil {
+			return nil, err
+		}
+		return sheet.Properties, nil
+	}
+
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.Title == sheetName {
+			return sheet.Properties, nil
+		}
+	}
+
+	return nil, fmt.Errorf("sheet %q not found in spreadsheet %s", sheetName, spreadsheetId)
+}
+
+// シートのプロパティをシートIDから取得
+func getSheetPropertiesById(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64) (*sheets.SheetProperties, error) {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.SheetId == sheetId {
+			return sheet.Properties, nil
+		}
+	}
+
+	return nil, fmt.Errorf("sheet id %d not found in spreadsheet %s", sheetId, spreadsheetId)
+}
+
+// 範囲の終端が始端以上で、シートのサイズに収まっているかをAPI呼び出し前に検証
+func validateRange(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string) error {
+	r, err := parseA1Range(a1)
+	if err != nil {
+		return err
+	}
+
+	props, err := getSheetProperties(ctx, srv, spreadsheetId, r.Sheet)
+	if err != nil {
+		return err
+	}
+
+	return checkRangeBounds(a1, r, props)
+}
+
+// 書き込み先の確認に使うシートのプロパティと結合セルを1回の取得で読み込む
+func getSheetsWithMerges(ctx context.Context, srv *sheets.Service, spreadsheetId string) (*sheets.Spreadsheet, error) {
+	return srv.Spreadsheets.Get(spreadsheetId).Fields("sheets(properties(sheetId,title,hidden,gridProperties),merges)").Context(ctx).Do()
+}
+
+// 取得済みのスプレッドシートからシートをタイトルで探す（タイトルが空の場合は先頭の表示されているシート）
+func findSheet(spreadsheet *sheets.Spreadsheet, spreadsheetId string, sheetName string) (*sheets.Sheet, error) {
+	if sheetName == "" {
+		if sheet := visibleSheet(spreadsheet); sheet != nil {
+			return sheet, nil
+		}
+		return nil, fmt.Errorf("spreadsheet %s has no visible sheets", spreadsheetId)
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.Title == sheetName {
+			return sheet, nil
+		}
+	}
+	return nil, fmt.Errorf("sheet %q not found in spreadsheet %s", sheetName, spreadsheetId)
+}
+
+// 解析済みの範囲がシートのサイズに収まっているかを検証
+func checkRangeBounds(a1 string, r a1Range, props *sheets.SheetProperties) error {
+	if props.GridProperties == nil {
+		return fmt.Errorf("sheet %q is not a grid sheet", props.Title)
+	}
+	rows, columns := props.GridProperties.RowCount, props.GridProperties.ColumnCount
+
+	if r.hasRows {
+		if r.StartRow >= rows || (!r.openEndRow && r.EndRow > rows) {
+			return fmt.Errorf("range %q exceeds sheet bounds: sheet has %d rows", a1, rows)
+		}
+	}
+	if r.hasColumns && r.EndColumn > columns {
+		return fmt.Errorf("range %q exceeds sheet bounds: sheet has %d columns (last is %s)", a1, columns, columnLetters(columns-1))
+	}
+
+	return nil
+}
+
+// シート名を省略した範囲に補うシート名（空の場合は警告を出してAPIに任せる）
+var defaultSheetName string
+
+// シート名のない範囲にデフォルトのシート名を補う（-r1c1 の場合は R1C1 表記の範囲を A1 表記に変換する）
+// 省略したままだとAPIは先頭の表示シートを使うため、意図しないタブへの書き込みを防ぐ
+func qualifyRange(a1 string) (string, error) {
+	if r1c1Ranges {
+		converted, err := rangeToA1(a1)
+		if err != nil {
+			return "", err
+		}
+		a1 = converted
+	}
+	if strings.Contains(a1, "!") {
+		return a1, nil
+	}
+	if defaultSheetName != "" {
+		return quoteSheetName(defaultSheetName) + "!" + a1, nil
+	}
+	log.Printf("warning: range %q has no sheet name; the first visible sheet will be used", a1)
+	return a1, nil
+}
+
+// 範囲の値を読み取り
+func readRange(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string) ([][]interface{}, error) {
+	a1, err := qualifyRange(a1)
+	if err != nil {
+		return nil, err
+	}
+
+	valueRange, err := srv.Spreadsheets.Values.Get(spreadsheetId, a1).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return valueRange.Values, nil
+}
+
+// 書き込み時の値の解釈方法（RAW はそのまま、USER_ENTERED は画面入力と同様に数式などを解釈）
+var valueInputOption = "RAW"
+
+// ValueInputOption の値を検証
+func validateValueInputOption(option string) error {
+	switch option {
+	case "RAW", "USER_ENTERED":
+		return nil
+	}
+	return fmt.Errorf("invalid value input option %q: must be RAW or USER_ENTERED", option)
+}
+
+// 書き込みの結果（更新されたセル・行・列の数と範囲）
+type UpdateResult struct {
+	UpdatedCells   int64
+	UpdatedRows    int64
+	UpdatedColumns int64
+	UpdatedRange   string
+}
+
+// Values.Update のレスポンスから書き込みの結果を取り出す
+func updateResultFrom(resp *sheets.UpdateValuesResponse) UpdateResult {
+	return UpdateResult{
+		UpdatedCells:   resp.UpdatedCells,
+		UpdatedRows:    resp.UpdatedRows,
+		UpdatedColumns: resp.UpdatedColumns,
+		UpdatedRange:   resp.UpdatedRange,
+	}
+}
+
+// 範囲に値を書き込み、書き込んだサイズを返す（dry-run の場合は何も書き込まず空の結果を返す）
+func writeRange(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string, values [][]interface{}) (UpdateResult, error) {
+	a1, err := qualifyRange(a1)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	r, err := parseA1Range(a1)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	// 範囲の検証と結合セルの確認は同じ取得結果を使う
+	spreadsheet, err := getSheetsWithMerges(ctx, srv, spreadsheetId)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	target, err := findSheet(spreadsheet, spreadsheetId, r.Sheet)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	if err := checkRangeBounds(a1, r, target.Properties); err != nil {
+		return UpdateResult{}, err
+	}
+	values, err = normalizeValues(values)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	// dry-run では結合の解除も行わず、重なる結合セルはエラーとして報告する
+	if err := checkMergedTarget(ctx, srv, spreadsheetId, a1, r, target, values, unmergeBeforeWrite && !dryRun); err != nil {
+		return UpdateResult{}, err
+	}
+	if dryRun {
+		return UpdateResult{}, previewWriteRange(ctx, srv, spreadsheetId, a1, values, os.Stdout)
+	}
+
+	valueRange := &sheets.ValueRange{
+		Range:          a1,
+		Values:         values,
+		MajorDimension: "ROWS",
+	}
+
+	resp, err := srv.Spreadsheets.Values.Update(spreadsheetId, a1, valueRange).ValueInputOption(valueInputOption).Context(ctx).Do()
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	return updateResultFrom(resp), nil
+}
+
+// 離れた複数の範囲に同じ値を1回の Values.BatchUpdate で書き込む（祝日の列をまとめて埋めるときなど）
+// "A:A" のように端が省略された範囲はシートの端までを埋める
+func fillRanges(ctx context.Context, srv *sheets.Service, spreadsheetId string, value interface{}, ranges []string) (UpdateResult, error) {
+	if len(ranges) == 0 {
+		return UpdateResult{}, fmt.Errorf("at least one range is required")
+	}
+	value, err := normalizeValue(value)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	// すべての範囲のシートのサイズは1回の取得で調べる
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	data := make([]*sheets.ValueRange, 0, len(ranges))
+	for _, a1 := range ranges {
+		a1, err := qualifyRange(a1)
+		if err != nil {
+			return UpdateResult{}, err
+		}
+		r, err := parseA1Range(a1)
+		if err != nil {
+			return UpdateResult{}, err
+		}
+		sheet, err := findSheet(spreadsheet, spreadsheetId, r.Sheet)
+		if err != nil {
+			return UpdateResult{}, err
+		}
+		if err := checkRangeBounds(a1, r, sheet.Properties); err != nil {
+			return UpdateResult{}, err
+		}
+		sheetRows, sheetColumns := sheet.Properties.GridProperties.RowCount, sheet.Properties.GridProperties.ColumnCount
+		if !r.hasRows {
+			r.StartRow, r.EndRow = 0, sheetRows
+		} else if r.openEndRow {
+			r.EndRow = sheetRows
+		}
+		if !r.hasColumns {
+			r.StartColumn, r.EndColumn = 0, sheetColumns
+		}
+
+		values := make([][]interface{}, r.EndRow-r.StartRow)
+		for i := range values {
+			values[i] = make([]interface{}, r.EndColumn-r.StartColumn)
+			for j := range values[i] {
+				values[i][j] = value
+			}
+		}
+		data = append(data, &sheets.ValueRange{
+			Range:          a1,
+			Values:         values,
+			MajorDimension: "ROWS",
+		})
+	}
+
+	if dryRun {
+		for _, valueRange := range data {
+			if err := previewWriteRange(ctx, srv, spreadsheetId, valueRange.Range, valueRange.Values, os.Stdout); err != nil {
+				return UpdateResult{}, err
+			}
+		}
+		return UpdateResult{}, nil
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateValuesRequest{
+		Data:             data,
+		ValueInputOption: valueInputOption,
+	}
+
+	resp, err := srv.Spreadsheets.Values.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	return UpdateResult{
+		UpdatedCells:   resp.TotalUpdatedCells,
+		UpdatedRows:    resp.TotalUpdatedRows,
+		UpdatedColumns: resp.TotalUpdatedColumns,
+	}, nil
+}
+
+// 範囲の値をクリア（書式や条件付き書式のルールは残る）
+func clearRange(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string) error {
+	if err := validateRange(ctx, srv, spreadsheetId, a1); err != nil {
+		return err
+	}
+
+	_, err := srv.Spreadsheets.Values.Clear(spreadsheetId, a1, &sheets.ClearValuesRequest{}).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 列を読み取り、最初の空セルの行番号（1始まり）を返す
+// columnA1 は "Sheet1!A:A" や "Sheet1!B2:B" のような1列の範囲
+func firstEmptyRow(ctx context.Context, srv *sheets.Service, spreadsheetId string, columnA1 string) (int, error) {
+	r, err := parseA1Range(columnA1)
+	if err != nil {
+		return 0, err
+	}
+	if !r.hasColumns || r.EndColumn-r.StartColumn != 1 {
+		return 0, fmt.Errorf("range %q must be a single column", columnA1)
+	}
+
+	valueRange, err := srv.Spreadsheets.Values.Get(spreadsheetId, columnA1).MajorDimension("COLUMNS").Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	var column []interface{}
+	if len(valueRange.Values) > 0 {
+		column = valueRange.Values[0]
+	}
+
+	// 末尾の空セルはAPIが省略するため、途中に空セルがなければ値の直後が最初の空行
+	offset := int(r.StartRow) + 1
+	for i, v := range column {
+		if fmt.Sprint(v) == "" {
+			return offset + i, nil
+		}
+	}
+	return offset + len(column), nil
+}
+
+// GridRange の終端が始端より後ろにあるかを検証（終端0は境界なしとして扱う）
+func validateGridRange(gr *sheets.GridRange) error {
+	if gr == nil {
+		return fmt.Errorf("grid range is required")
+	}
+	if gr.StartRowIndex < 0 || gr.StartColumnIndex < 0 {
+		return fmt.Errorf("grid range has a negative start index")
+	}
+	if gr.EndRowIndex != 0 && gr.EndRowIndex <= gr.StartRowIndex {
+		return fmt.Errorf("grid range end row %d must be after start row %d", gr.EndRowIndex, gr.StartRowIndex)
+	}
+	if gr.EndColumnIndex != 0 && gr.EndColumnIndex <= gr.StartColumnIndex {
+		return fmt.Errorf("grid range end column %d must be after start column %d", gr.EndColumnIndex, gr.StartColumnIndex)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 範囲の検証と結合セルの確認は1回の取得で済ませ、書き込みと合わせて2回の呼び出しになる
+func TestWriteRangeFetchesSheetOnce(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	srv := fake.service(t)
+
+	if _, err := writeRange(context.Background(), srv, "fake", "Sheet1!A1:B2", [][]interface{}{{1, 2}, {3, 4}}); err != nil {
+		t.Fatalf("writeRange: %v", err)
+	}
+	if fake.calls != 2 {
+		t.Errorf("made %d API calls, want 2", fake.calls)
+	}
+}
+
+// 取得したシートの結合セルとサイズの両方で書き込み先を検証する
+func TestWriteRangeChecksBoundsAndMerges(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 10, 5)
+	fake.spreadsheet.Sheets[0].Merges = []*sheets.GridRange{
+		{SheetId: 1, StartRowIndex: 0, EndRowIndex: 2, StartColumnIndex: 0, EndColumnIndex: 2},
+	}
+	srv := fake.service(t)
+	ctx := context.Background()
+
+	_, err := writeRange(ctx, srv, "fake", "Sheet1!A1", [][]interface{}{{1, 2}})
+	if err == nil || !strings.Contains(err.Error(), "merged cells A1:B2") {
+		t.Errorf("writeRange into merged cells: err = %v, want merged cells error", err)
+	}
+	_, err = writeRange(ctx, srv, "fake", "Sheet1!F1", [][]interface{}{{1}})
+	if err == nil || !strings.Contains(err.Error(), "exceeds sheet bounds") {
+		t.Errorf("writeRange outside the sheet: err = %v, want bounds error", err)
+	}
+	if fake.calls != 2 {
+		t.Errorf("made %d API calls, want 2", fake.calls)
+	}
+}
+
+// 複数の範囲を埋めるときもシートのサイズの取得は1回だけ
+func TestFillRangesFetchesSheetOnce(t *testing.T) {
+	fake := newFakeSheets("Sheet1", 4, 3)
+	srv := fake.service(t)
+
+	if _, err := fillRanges(context.Background(), srv, "fake", "x", []string{"Sheet1!A:A", "Sheet1!C2:C"}); err != nil {
+		t.Fatalf("fillRanges: %v", err)
+	}
+	if fake.calls != 2 {
+		t.Errorf("made %d API calls, want 2", fake.calls)
+	}
+	if got := fake.read("Sheet1!C4"); len(got) != 1 || got[0][0] != "x" {
+		t.Errorf("Sheet1!C4 = %v, want [[x]]", got)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// セルの計算結果を Go の値として取り出す（値がなければ表示文字列、それもなければ nil）
+func cellValue(cell *sheets.CellData) interface{} {
+	if cell == nil {
+		return nil
+	}
+	if ev := cell.EffectiveValue; ev != nil {
+		switch {
+		case ev.NumberValue != nil:
+			return *ev.NumberValue
+		case ev.StringValue != nil:
+			return *ev.StringValue
+		case ev.BoolValue != nil:
+			return *ev.BoolValue
+		case ev.ErrorValue != nil:
+			return cell.FormattedValue
+		}
+	}
+	if cell.FormattedValue != "" {
+		return cell.FormattedValue
+	}
+	return nil
+}
+
+// GridData を2次元の値に変換
+func gridDataValues(data []*sheets.GridData) [][]interface{} {
+	var values [][]interface{}
+	for _, grid := range data {
+		for _, rowData := range grid.RowData {
+			row := make([]interface{}, len(rowData.Values))
+			for i, cell := range rowData.Values {
+				row[i] = cellValue(cell)
+			}
+			values = append(values, row)
+		}
+	}
+	return values
+}
+
+// 全シートのデータを1回のGetで取得し、シート名ごとの2次元の値として返す
+// ranges を指定した場合はその範囲だけを取得する
+func getAllSheetsData(ctx context.Context, srv *sheets.Service, spreadsheetId string, ranges ...string) (map[string][][]interface{}, error) {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Ranges(ranges...).IncludeGridData(true).
+		Fields("sheets(properties.title,data.rowData.values(effectiveValue,formattedValue))").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string][][]interface{}, len(spreadsheet.Sheets))
+	for _, sheet := range spreadsheet.Sheets {
+		data[sheet.Properties.Title] = gridDataValues(sheet.Data)
+	}
+
+	return data, nil
+}
+
+// DataFilter（A1 範囲やデベロッパーメタデータ）に一致する範囲だけを含むスプレッドシートを取得
+func getByDataFilter(ctx context.Context, srv *sheets.Service, spreadsheetId string, filters []*sheets.DataFilter) (*sheets.Spreadsheet, error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("at least one data filter is required")
+	}
+
+	getRequest := &sheets.GetSpreadsheetByDataFilterRequest{
+		DataFilters:     filters,
+		IncludeGridData: true,
+	}
+
+	spreadsheet, err := srv.Spreadsheets.GetByDataFilter(spreadsheetId, getRequest).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	return spreadsheet, nil
+}
+
+// DataFilter に一致する範囲の値を取得し、一致した範囲（A1 表記）ごとに返す
+// デベロッパーメタデータで範囲を探せば、レイアウトが変わっても自分のデータ領域を特定できる
+func batchGetByDataFilter(ctx context.Context, srv *sheets.Service, spreadsheetId string, filters []*sheets.DataFilter) (map[string][][]interface{}, error) {
+	if len(filters) == 0 {
+		return nil, fmt.Errorf("at least one data filter is required")
+	}
+
+	batchGetRequest := &sheets.BatchGetValuesByDataFilterRequest{
+		DataFilters:    filters,
+		MajorDimension: "ROWS",
+	}
+
+	resp, err := srv.Spreadsheets.Values.BatchGetByDataFilter(spreadsheetId, batchGetRequest).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][][]interface{}, len(resp.ValueRanges))
+	for _, matched := range resp.ValueRanges {
+		if matched.ValueRange != nil {
+			values[matched.ValueRange.Range] = matched.ValueRange.Values
+		}
+	}
+
+	return values, nil
+}
+
+// 見出しを重複しないように整える（重複は "_2"、"_3" を付け、空の見出しは列名にする）
+func uniqueHeaders(row []interface{}) []string {
+	headers := make([]string, len(row))
+	seen := map[string]bool{}
+	for i, v := range row {
+		header := strings.TrimSpace(fmt.Sprint(v))
+		if header == "" {
+			header = columnLetters(int64(i))
+		}
+		unique := header
+		for n := 2; seen[unique]; n++ {
+			unique = fmt.Sprintf("%s_%d", header, n)
+		}
+		seen[unique] = true
+		headers[i] = unique
+	}
+	return headers
+}
+
+// シート全体を読み取り、1行目を見出しとして行ごとのマップで返す
+func readAsMaps(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetName string) ([]map[string]string, error) {
+	values, err := readRange(ctx, srv, spreadsheetId, quoteSheetName(sheetName))
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return []map[string]string{}, nil
+	}
+
+	headers := uniqueHeaders(values[0])
+	records := make([]map[string]string, 0, len(values)-1)
+	for _, row := range values[1:] {
+		record := make(map[string]string, len(headers))
+		for i, header := range headers {
+			if i < len(row) {
+				record[header] = fmt.Sprint(row[i])
+			} else {
+				record[header] = ""
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// 範囲の各セルの背景色を取得（書式が設定されていないセルは白）
+// テンプレートで網掛けされているセル（祝日など）を見分けるために使う
+func readBackgroundColors(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange) ([][]*sheets.Color, error) {
+	if gridRange == nil {
+		return nil, fmt.Errorf("grid range is required")
+	}
+	props, err := getSheetPropertiesById(ctx, srv, spreadsheetId, gridRange.SheetId)
+	if err != nil {
+		return nil, err
+	}
+	a1, resolved, err := gridRangeA1(props, gridRange)
+	if err != nil {
+		return nil, err
+	}
+
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Ranges(a1).IncludeGridData(true).
+		Fields("sheets.data.rowData.values.effectiveFormat.backgroundColor").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, columns := gridRangeSize(resolved)
+	colors := make([][]*sheets.Color, rows)
+	for r := range colors {
+		colors[r] = make([]*sheets.Color, columns)
+		for c := range colors[r] {
+			colors[r][c] = &sheets.Color{Red: 1, Green: 1, Blue: 1}
+		}
+	}
+
+	// 末尾の空の行・セルはレスポンスから省略されるため、既定の白のままにする
+	for _, sheet := range spreadsheet.Sheets {
+		for _, data := range sheet.Data {
+			for r, rowData := range data.RowData {
+				if int64(r) >= rows {
+					break
+				}
+				for c, cell := range rowData.Values {
+					if int64(c) >= columns {
+						break
+					}
+					if cell.EffectiveFormat != nil && cell.EffectiveFormat.BackgroundColor != nil {
+						colors[r][c] = cell.EffectiveFormat.BackgroundColor
+					}
+				}
+			}
+		}
+	}
+
+	return colors, nil
+}
+
+// 範囲の各セルの入力規則（プルダウンなど）を取得（規則のないセルは nil）
+// 生成前にテンプレートのプルダウンが壊れていないかを確認するために使う
+func getDataValidations(ctx context.Context, srv *sheets.Service, spreadsheetId string, gridRange *sheets.GridRange) ([][]*sheets.DataValidationRule, error) {
+	if gridRange == nil {
+		return nil, fmt.Errorf("grid range is required")
+	}
+	props, err := getSheetPropertiesById(ctx, srv, spreadsheetId, gridRange.SheetId)
+	if err != nil {
+		return nil, err
+	}
+	a1, resolved, err := gridRangeA1(props, gridRange)
+	if err != nil {
+		return nil, err
+	}
+
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Ranges(a1).IncludeGridData(true).
+		Fields("sheets.data.rowData.values.dataValidation").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, columns := gridRangeSize(resolved)
+	rules := make([][]*sheets.DataValidationRule, rows)
+	for r := range rules {
+		rules[r] = make([]*sheets.DataValidationRule, columns)
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		for _, data := range sheet.Data {
+			for r, rowData := range data.RowData {
+				if int64(r) >= rows {
+					break
+				}
+				for c, cell := range rowData.Values {
+					if int64(c) >= columns {
+						break
+					}
+					rules[r][c] = cell.DataValidation
+				}
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// 範囲の各セルの計算結果の数値を取得（数値でないセルや空のセルは nil）
+// 合計などの数式のセルも、表示形式に左右されず計算された値を float64 で読み取れる
+func readEffectiveNumbers(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string) ([][]*float64, error) {
+	a1, err := qualifyRange(a1)
+	if err != nil {
+		return nil, err
+	}
+
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Ranges(a1).IncludeGridData(true).
+		Fields("sheets.data.rowData.values.effectiveValue.numberValue").Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	var numbers [][]*float64
+	for _, sheet := range spreadsheet.Sheets {
+		for _, data := range sheet.Data {
+			for _, rowData := range data.RowData {
+				row := make([]*float64, len(rowData.Values))
+				for i, cell := range rowData.Values {
+					if cell.EffectiveValue != nil {
+						row[i] = cell.EffectiveValue.NumberValue
+					}
+				}
+				numbers = append(numbers, row)
+			}
+		}
+	}
+
+	return numbers, nil
+}
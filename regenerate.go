@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 範囲のうち指定した列（"AH" など）の部分を A1 表記で返す
+func columnSubranges(a1 string, columns []string) ([]string, error) {
+	r, err := parseA1Range(a1)
+	if err != nil {
+		return nil, err
+	}
+	if !r.hasColumns {
+		return nil, fmt.Errorf("range %q must specify columns to preserve columns", a1)
+	}
+
+	rows := ""
+	if r.hasRows {
+		rows = strconv.FormatInt(r.StartRow+1, 10)
+	}
+	endRows := ""
+	if r.hasRows && !r.openEndRow {
+		endRows = strconv.FormatInt(r.EndRow, 10)
+	}
+
+	prefix := ""
+	if r.Sheet != "" {
+		prefix = quoteSheetName(r.Sheet) + "!"
+	}
+
+	subranges := make([]string, 0, len(columns))
+	for _, column := range columns {
+		col := columnIndex(column)
+		if col < r.StartColumn || col >= r.EndColumn {
+			return nil, fmt.Errorf("column %s is outside range %q", column, a1)
+		}
+		letters := columnLetters(col)
+		subranges = append(subranges, prefix+letters+rows+":"+letters+endRows)
+	}
+	return subranges, nil
+}
+
+// 範囲の値をクリアするが、preserveColumns の列（合計の =SUM() など）は数式を読み取っておき、クリア後に書き戻す
+// 条件付き書式のルールはセルではなくシートに属し、値のクリアでは削除されないため、残業の強調表示などはそのまま残る
+func clearPreservingFormulas(ctx context.Context, srv *sheets.Service, spreadsheetId string, a1 string, preserveColumns []string) error {
+	a1, err := qualifyRange(a1)
+	if err != nil {
+		return err
+	}
+
+	preserveRanges, err := columnSubranges(a1, preserveColumns)
+	if err != nil {
+		return err
+	}
+
+	var preserved []*sheets.ValueRange
+	if len(preserveRanges) > 0 {
+		resp, err := srv.Spreadsheets.Values.BatchGet(spreadsheetId).Ranges(preserveRanges...).ValueRenderOption("FORMULA").Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("unable to read formulas to preserve: %w", err)
+		}
+		preserved = resp.ValueRanges
+	}
+
+	if err := clearRange(ctx, srv, spreadsheetId, a1); err != nil {
+		return err
+	}
+
+	if len(preserved) == 0 {
+		return nil
+	}
+
+	// 数式として解釈させるため USER_ENTERED で書き戻す
+	batchUpdateRequest := &sheets.BatchUpdateValuesRequest{
+		Data:             preserved,
+		ValueInputOption: "USER_ENTERED",
+	}
+	_, err = srv.Spreadsheets.Values.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("unable to restore preserved formulas: %w", err)
+	}
+
+	return nil
+}
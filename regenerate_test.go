@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 値・書式のクリアでは、シートに属する条件付き書式のルールは削除されない
+func TestClearKeepsConditionalFormats(t *testing.T) {
+	fake := newFakeSheets("2024年4月", 10, 33)
+	rules := []*sheets.ConditionalFormatRule{
+		{
+			Ranges: []*sheets.GridRange{{SheetId: 1, StartRowIndex: 1, EndRowIndex: 10, StartColumnIndex: 1, EndColumnIndex: 32}},
+			BooleanRule: &sheets.BooleanRule{
+				Condition: &sheets.BooleanCondition{Type: "NUMBER_GREATER", Values: []*sheets.ConditionValue{{UserEnteredValue: "8"}}},
+				Format:    &sheets.CellFormat{BackgroundColor: &sheets.Color{Red: 1, Green: 0.8, Blue: 0.8}},
+			},
+		},
+		{
+			Ranges: []*sheets.GridRange{{SheetId: 1, StartRowIndex: 1, EndRowIndex: 10, StartColumnIndex: 32, EndColumnIndex: 33}},
+			GradientRule: &sheets.GradientRule{
+				Minpoint: &sheets.InterpolationPoint{Type: "MIN", Color: &sheets.Color{Green: 1}},
+				Maxpoint: &sheets.InterpolationPoint{Type: "MAX", Color: &sheets.Color{Red: 1}},
+			},
+		},
+	}
+	fake.spreadsheet.Sheets[0].ConditionalFormats = rules
+	fake.write("'2024年4月'!A2", [][]interface{}{{"山田", 8, 9, "=SUM(B2:C2)"}})
+
+	srv := fake.service(t)
+	ctx := context.Background()
+
+	if err := clearRange(ctx, srv, "fake", "'2024年4月'!B2:C10"); err != nil {
+		t.Fatalf("clearRange: %v", err)
+	}
+	if err := clearPreservingFormulas(ctx, srv, "fake", "'2024年4月'!A2:D10", []string{"D"}); err != nil {
+		t.Fatalf("clearPreservingFormulas: %v", err)
+	}
+	if err := clearFormatting(ctx, srv, "fake", &sheets.GridRange{SheetId: 1, StartRowIndex: 1, EndRowIndex: 10, StartColumnIndex: 0, EndColumnIndex: 33}); err != nil {
+		t.Fatalf("clearFormatting: %v", err)
+	}
+
+	got, err := getConditionalFormats(ctx, srv, "fake", 1)
+	if err != nil {
+		t.Fatalf("getConditionalFormats: %v", err)
+	}
+	if !reflect.DeepEqual(got, rules) {
+		t.Errorf("conditional formats changed after clearing: %+v", got)
+	}
+	for _, request := range fake.requests {
+		if request.DeleteConditionalFormatRule != nil || request.UpdateConditionalFormatRule != nil {
+			t.Errorf("clearing sent a conditional format request: %+v", request)
+		}
+	}
+
+	if len(fake.cleared) != 2 {
+		t.Errorf("cleared ranges = %q, want two clears", fake.cleared)
+	}
+	if got := fake.read("'2024年4月'!A2:D2"); !reflect.DeepEqual(got, [][]interface{}{{"", "", "", "=SUM(B2:C2)"}}) {
+		t.Errorf("values after clearing = %#v, want only the preserved formula", got)
+	}
+}
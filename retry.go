@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// 指数バックオフによるリトライの設定
+type retryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var defaultRetryConfig = retryConfig{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    16 * time.Second,
+}
+
+// 設定値がすべて正の値で、最大待ち時間が初回の待ち時間以上かを検証
+func (c retryConfig) validate() error {
+	if c.MaxAttempts <= 0 {
+		return fmt.Errorf("max retries must be positive, got %d", c.MaxAttempts)
+	}
+	if c.BaseDelay <= 0 {
+		return fmt.Errorf("backoff must be positive, got %s", c.BaseDelay)
+	}
+	if c.MaxDelay < c.BaseDelay {
+		return fmt.Errorf("max backoff %s must not be less than backoff %s", c.MaxDelay, c.BaseDelay)
+	}
+	return nil
+}
+
+// 一時的なエラー（429 と 5xx）かどうかを判定
+func isRetryable(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+}
+
+// 429 かどうかを判定（レート制限で拒否されたリクエストはサーバーで実行されていない）
+func isRateLimited(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests
+}
+
+// 404 かどうかを判定
+func isNotFound(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// fn を一時的なエラーの間だけ指数バックオフでリトライ
+func (c retryConfig) do(ctx context.Context, fn func() error) error {
+	return c.doWhen(ctx, isRetryable, fn)
+}
+
+// fn を shouldRetry が true を返すエラーの間だけ指数バックオフでリトライ
+func (c retryConfig) doWhen(ctx context.Context, shouldRetry func(error) bool, fn func() error) error {
+	delay := c.BaseDelay
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || !shouldRetry(err) || attempt >= c.MaxAttempts {
+			return err
+		}
+
+		// 同時に失敗したリクエストが揃って再送しないようにジッターを加える
+		wait := delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+		delay *= 2
+		if delay > c.MaxDelay {
+			delay = c.MaxDelay
+		}
+	}
+}
+
+// デフォルトの設定でリトライ
+func withRetry(ctx context.Context, fn func() error) error {
+	return defaultRetryConfig.do(ctx, fn)
+}
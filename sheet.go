@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// シートを追加し、作成されたシートのIDを返す
+// index は新しいタブの位置（0始まり）で、負の値の場合は末尾に追加
+func addSheet(ctx context.Context, srv *sheets.Service, spreadsheetId string, title string, index int) (int64, error) {
+	properties := &sheets.SheetProperties{
+		Title: title,
+	}
+
+	if index >= 0 {
+		spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("sheets.properties.sheetId").Context(ctx).Do()
+		if err != nil {
+			return 0, err
+		}
+		if index > len(spreadsheet.Sheets) {
+			return 0, fmt.Errorf("sheet index %d is out of range: spreadsheet has %d sheets", index, len(spreadsheet.Sheets))
+		}
+		properties.Index = int64(index)
+		// 先頭（0）に追加する場合も省略されないようにする
+		properties.ForceSendFields = []string{"Index"}
+	}
+
+	addSheetRequest := sheets.Request{
+		AddSheet: &sheets.AddSheetRequest{
+			Properties: properties,
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&addSheetRequest},
+	}
+
+	resp, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+
+	return addedSheetId(resp.Replies, 0)
+}
+
+// 複数のシートの名前を1回のBatchUpdateで変更（renames はシートIDから新しい名前への対応）
+func renameSheets(ctx context.Context, srv *sheets.Service, spreadsheetId string, renames map[int64]string) error {
+	sheetIds := make([]int64, 0, len(renames))
+	usedBy := map[string]int64{}
+	for sheetId, title := range renames {
+		if title == "" {
+			return fmt.Errorf("new name for sheet %d is empty", sheetId)
+		}
+		if other, ok := usedBy[title]; ok {
+			return fmt.Errorf("sheets %d and %d would both be renamed to %q", other, sheetId, title)
+		}
+		usedBy[title] = sheetId
+		sheetIds = append(sheetIds, sheetId)
+	}
+	sort.Slice(sheetIds, func(i, j int) bool { return sheetIds[i] < sheetIds[j] })
+
+	requests := make([]*sheets.Request, 0, len(sheetIds))
+	for _, sheetId := range sheetIds {
+		requests = append(requests, &sheets.Request{
+			UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+				Properties: &sheets.SheetProperties{
+					SheetId: sheetId,
+					Title:   renames[sheetId],
+				},
+				Fields: "title",
+			},
+		})
+	}
+
+	_, err := batchUpdate(ctx, srv, spreadsheetId, requests)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 月の勤務表のシートを作成時のサイズ付きで定義
+// 列は日数 + 2（社員名と合計）、行は社員数 + 見出しの行数にし、不要な空の行・列を作らない
+// createSpreadsheet の initialSheets に渡して使う（-employees を指定した場合の CreateSchedule）
+func scheduleSheet(title string, year int, month int, employees int, headerRows int) (*sheets.Sheet, error) {
+	days, _, err := monthInfo(year, month)
+	if err != nil {
+		return nil, err
+	}
+	if employees <= 0 || headerRows <= 0 {
+		return nil, fmt.Errorf("employees and headerRows must be positive, got %d and %d", employees, headerRows)
+	}
+
+	return &sheets.Sheet{
+		Properties: &sheets.SheetProperties{
+			Title: title,
+			GridProperties: &sheets.GridProperties{
+				ColumnCount: int64(days + 2),
+				RowCount:    int64(employees + headerRows),
+			},
+		},
+	}, nil
+}
+
+// シートの表示・非表示を切り替え
+// 表示されているシートが1枚だけの場合、それを非表示にすることはAPIで拒否されるため事前にエラーを返す
+func setSheetHidden(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, hidden bool) error {
+	if hidden {
+		spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("sheets.properties(sheetId,hidden)").Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+
+		found := false
+		otherVisible := 0
+		for _, sheet := range spreadsheet.Sheets {
+			if sheet.Properties.SheetId == sheetId {
+				found = true
+			} else if !sheet.Properties.Hidden {
+				otherVisible++
+			}
+		}
+		if !found {
+			return fmt.Errorf("sheet id %d not found in spreadsheet %s", sheetId, spreadsheetId)
+		}
+		if otherVisible == 0 {
+			return fmt.Errorf("cannot hide sheet %d: it is the only visible sheet", sheetId)
+		}
+	}
+
+	updateSheetPropertiesRequest := &sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId: sheetId,
+				Hidden:  hidden,
+				// 再表示（false）も送信する
+				ForceSendFields: []string{"Hidden"},
+			},
+			Fields: "hidden",
+		},
+	}
+
+	_, err := batchUpdate(ctx, srv, spreadsheetId, []*sheets.Request{updateSheetPropertiesRequest})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// シートのタブを newIndex（0始まり、移動後の位置）に移動
+func moveSheet(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetId int64, newIndex int) error {
+	spreadsheet, err := srv.Spreadsheets.Get(spreadsheetId).Fields("sheets.properties(sheetId,index)").Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+	if newIndex < 0 || newIndex >= len(spreadsheet.Sheets) {
+		return fmt.Errorf("sheet index %d is out of range: spreadsheet has %d sheets", newIndex, len(spreadsheet.Sheets))
+	}
+
+	current := int64(-1)
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.SheetId == sheetId {
+			current = sheet.Properties.Index
+			break
+		}
+	}
+	if current < 0 {
+		return fmt.Errorf("sheet id %d not found in spreadsheet %s", sheetId, spreadsheetId)
+	}
+
+	index, move := moveSheetIndex(current, int64(newIndex))
+	if !move {
+		return nil
+	}
+
+	updateSheetPropertiesRequest := &sheets.Request{
+		UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId: sheetId,
+				Index:   index,
+				// 先頭（0）への移動も送信する
+				ForceSendFields: []string{"Index"},
+			},
+			Fields: "index",
+		},
+	}
+
+	_, err = batchUpdate(ctx, srv, spreadsheetId, []*sheets.Request{updateSheetPropertiesRequest})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// 移動後の位置 newIndex にするために API へ送る index を返す（移動が不要なら move は false）
+// API の index は移動前の並びでの挿入位置として扱われるため、右へ移動する場合は1つ大きい値を送る
+// （例: S1, S2, S3 の S1 を2番目に移動するには index に 2 を指定する）
+func moveSheetIndex(current int64, newIndex int64) (index int64, move bool) {
+	if newIndex == current {
+		return current, false
+	}
+	if newIndex > current {
+		return newIndex + 1, true
+	}
+	return newIndex, true
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestMoveSheetIndex(t *testing.T) {
+	// S0, S1, S2, S3 の並びで current のシートを移動後に newIndex の位置にする
+	tests := []struct {
+		name      string
+		current   int64
+		newIndex  int64
+		wantIndex int64
+		wantMove  bool
+	}{
+		{name: "stay in place", current: 1, newIndex: 1, wantIndex: 1, wantMove: false},
+		{name: "move left by one", current: 2, newIndex: 1, wantIndex: 1, wantMove: true},
+		{name: "move right by one", current: 1, newIndex: 2, wantIndex: 3, wantMove: true},
+		{name: "move to the start", current: 3, newIndex: 0, wantIndex: 0, wantMove: true},
+		{name: "move to the end", current: 0, newIndex: 3, wantIndex: 4, wantMove: true},
+		{name: "first stays first", current: 0, newIndex: 0, wantIndex: 0, wantMove: false},
+		{name: "last stays last", current: 3, newIndex: 3, wantIndex: 3, wantMove: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index, move := moveSheetIndex(tt.current, tt.newIndex)
+			if index != tt.wantIndex || move != tt.wantMove {
+				t.Fatalf("moveSheetIndex(%d, %d) = %d, %v; want %d, %v", tt.current, tt.newIndex, index, move, tt.wantIndex, tt.wantMove)
+			}
+			if !move {
+				return
+			}
+			// API と同じく、移動前の並びで index の位置に挿入して結果の位置を確かめる
+			order := []int64{0, 1, 2, 3}
+			moved := make([]int64, 0, len(order))
+			for i, s := range order {
+				if int64(i) == index {
+					moved = append(moved, tt.current)
+				}
+				if s != tt.current {
+					moved = append(moved, s)
+				}
+			}
+			if index == int64(len(order)) {
+				moved = append(moved, tt.current)
+			}
+			if moved[tt.newIndex] != tt.current {
+				t.Errorf("after sending index %d the sheet is not at %d: %v", index, tt.newIndex, moved)
+			}
+		})
+	}
+}
+
+func TestScheduleSheet(t *testing.T) {
+	tests := []struct {
+		year, month, employees, headerRows int
+		wantColumns, wantRows              int64
+	}{
+		{year: 2024, month: 2, employees: 10, headerRows: 4, wantColumns: 31, wantRows: 14},
+		{year: 2023, month: 2, employees: 1, headerRows: 3, wantColumns: 30, wantRows: 4},
+		{year: 2024, month: 4, employees: 25, headerRows: 5, wantColumns: 32, wantRows: 30},
+		{year: 2024, month: 12, employees: 3, headerRows: 4, wantColumns: 33, wantRows: 7},
+	}
+	for _, tt := range tests {
+		sheet, err := scheduleSheet("勤務表", tt.year, tt.month, tt.employees, tt.headerRows)
+		if err != nil {
+			t.Fatalf("scheduleSheet(%d/%d): %v", tt.year, tt.month, err)
+		}
+		grid := sheet.Properties.GridProperties
+		if grid.ColumnCount != tt.wantColumns || grid.RowCount != tt.wantRows {
+			t.Errorf("scheduleSheet(%d/%d, %d employees, %d header rows) = %dx%d, want %dx%d",
+				tt.year, tt.month, tt.employees, tt.headerRows, grid.ColumnCount, grid.RowCount, tt.wantColumns, tt.wantRows)
+		}
+	}
+
+	if _, err := scheduleSheet("勤務表", 2024, 4, 0, 4); err == nil {
+		t.Error("scheduleSheet accepted zero employees")
+	}
+	if _, err := scheduleSheet("勤務表", 2024, 13, 5, 4); err == nil {
+		t.Error("scheduleSheet accepted month 13")
+	}
+}
+
+// S0, S1, S2, S3 の並びで、移動後に newIndex の位置になるよう API の index を送る
+func TestMoveSheet(t *testing.T) {
+	tests := []struct {
+		name      string
+		current   int64
+		newIndex  int
+		wantIndex int64
+		wantSent  bool
+	}{
+		{name: "stay in place", current: 1, newIndex: 1, wantSent: false},
+		{name: "move left by one", current: 2, newIndex: 1, wantIndex: 1, wantSent: true},
+		{name: "move right by one", current: 1, newIndex: 2, wantIndex: 3, wantSent: true},
+		{name: "move to the start", current: 3, newIndex: 0, wantIndex: 0, wantSent: true},
+		{name: "move to the end", current: 0, newIndex: 3, wantIndex: 4, wantSent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSheets("S0", 10, 5)
+			for i := int64(1); i < 4; i++ {
+				fake.spreadsheet.Sheets = append(fake.spreadsheet.Sheets, &sheets.Sheet{
+					Properties: &sheets.SheetProperties{SheetId: i + 1, Index: i, Title: fmt.Sprintf("S%d", i)},
+				})
+			}
+			srv := fake.service(t)
+
+			if err := moveSheet(context.Background(), srv, "fake", tt.current+1, tt.newIndex); err != nil {
+				t.Fatalf("moveSheet: %v", err)
+			}
+			if !tt.wantSent {
+				if len(fake.requests) != 0 {
+					t.Errorf("sent %d requests for a move in place", len(fake.requests))
+				}
+				return
+			}
+			if len(fake.requests) != 1 {
+				t.Fatalf("sent %d requests, want 1", len(fake.requests))
+			}
+			if got := fake.requests[0].UpdateSheetProperties.Properties.Index; got != tt.wantIndex {
+				t.Errorf("sent index %d, want %d", got, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestMoveSheetOutOfRange(t *testing.T) {
+	fake := newFakeSheets("S0", 10, 5)
+	srv := fake.service(t)
+	if err := moveSheet(context.Background(), srv, "fake", 1, 1); err == nil {
+		t.Error("moveSheet to index 1 of a single-sheet spreadsheet succeeded")
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 反復計算の設定を変更（enabled が false の場合は反復計算を無効にする）
+func setIterativeCalc(ctx context.Context, srv *sheets.Service, spreadsheetId string, enabled bool, maxIterations int64, threshold float64) error {
+	properties := &sheets.SpreadsheetProperties{}
+	if enabled {
+		if maxIterations <= 0 {
+			return fmt.Errorf("maxIterations must be positive when iterative calculation is enabled, got %d", maxIterations)
+		}
+		if threshold < 0 {
+			return fmt.Errorf("threshold must not be negative, got %v", threshold)
+		}
+		properties.IterativeCalculationSettings = &sheets.IterativeCalculationSettings{
+			MaxIterations:        maxIterations,
+			ConvergenceThreshold: threshold,
+		}
+	}
+
+	// フィールドを指定して設定を省略すると反復計算が無効になる
+	updateRequest := sheets.Request{
+		UpdateSpreadsheetProperties: &sheets.UpdateSpreadsheetPropertiesRequest{
+			Properties: properties,
+			Fields:     "iterativeCalculationSettings",
+		},
+	}
+
+	batchUpdateRequest := &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{&updateRequest},
+	}
+
+	_, err := srv.Spreadsheets.BatchUpdate(spreadsheetId, batchUpdateRequest).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
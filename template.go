@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// テンプレートの見出しとして確認する先頭の行数
+const templateHeaderRows = 5
+
+// 合計列の見出し
+const totalsColumnHeader = "合計"
+
+// テンプレートに想定どおりのシートや範囲があるかを確認し、見つかった問題と警告の一覧を返す
+// 日付の見出し行（1, 2, 3, ...）があるシートを勤務表のシートとみなし、年（A1）・月（A3）のセルと合計列を確認する
+// 集計・メモ・計算用などのそれ以外のシートは問題にせず、確認しなかったことを警告として返す
+func validateTemplate(ctx context.Context, srv *sheets.Service, templateId string) (problems []string, warnings []string, err error) {
+	if templateId == "" {
+		return []string{"template spreadsheet id is empty"}, nil, nil
+	}
+
+	var ranges []string
+	spreadsheet, err := srv.Spreadsheets.Get(templateId).Fields("sheets.properties").Context(ctx).Do()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties.SheetType == "GRID" {
+			ranges = append(ranges, fmt.Sprintf("%s!1:%d", quoteSheetName(sheet.Properties.Title), templateHeaderRows))
+		}
+	}
+
+	if len(spreadsheet.Sheets) == 0 {
+		return []string{"template has no sheets"}, nil, nil
+	}
+	if len(ranges) == 0 {
+		return []string{"template has no grid sheets"}, nil, nil
+	}
+
+	spreadsheet, err = srv.Spreadsheets.Get(templateId).Ranges(ranges...).IncludeGridData(true).
+		Fields("sheets(properties,merges,data(rowData(values(formattedValue))))").Context(ctx).Do()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	schedules := 0
+	for _, sheet := range spreadsheet.Sheets {
+		title := sheet.Properties.Title
+		if sheet.Properties.SheetType != "GRID" {
+			warnings = append(warnings, fmt.Sprintf("sheet %q is not a grid sheet; it is copied without checks", title))
+			continue
+		}
+
+		var rows [][]string
+		for _, data := range sheet.Data {
+			for _, rowData := range data.RowData {
+				var row []string
+				for _, cell := range rowData.Values {
+					row = append(row, strings.TrimSpace(cell.FormattedValue))
+				}
+				rows = append(rows, row)
+			}
+		}
+
+		if !hasDateHeaderRow(rows) {
+			warnings = append(warnings, fmt.Sprintf("sheet %q has no date header row (1, 2, 3, ...) in the first %d rows; it is treated as a non-schedule sheet and copied without checks", title, templateHeaderRows))
+			continue
+		}
+		schedules++
+
+		if sheet.Properties.GridProperties.RowCount < 3 {
+			problems = append(problems, fmt.Sprintf("sheet %q has fewer than 3 rows; the year (A1) and month (A3) cells are missing", title))
+		}
+
+		// 年・月のセルが結合セルの途中にあると書き込めない
+		for _, merge := range sheet.Merges {
+			for _, cell := range []struct {
+				name string
+				row  int64
+			}{{"A1", 0}, {"A3", 2}} {
+				inside := merge.StartColumnIndex == 0 && merge.StartRowIndex <= cell.row && cell.row < merge.EndRowIndex
+				if inside && merge.StartRowIndex != cell.row {
+					problems = append(problems, fmt.Sprintf("sheet %q: %s is inside a merged range that does not start at it", title, cell.name))
+				}
+			}
+		}
+
+		if !hasCell(rows, totalsColumnHeader) {
+			problems = append(problems, fmt.Sprintf("sheet %q: no totals column header %q found in the first %d rows", title, totalsColumnHeader, templateHeaderRows))
+		}
+	}
+
+	if schedules == 0 {
+		problems = append(problems, fmt.Sprintf("template has no schedule sheet: no sheet has a date header row (1, 2, 3, ...) in the first %d rows", templateHeaderRows))
+	}
+
+	return problems, warnings, nil
+}
+
+// 1, 2, 3 と連続する日付の見出しを含む行があるか
+func hasDateHeaderRow(rows [][]string) bool {
+	for _, row := range rows {
+		for i := 0; i+2 < len(row); i++ {
+			if strings.TrimSuffix(row[i], "日") == "1" && strings.TrimSuffix(row[i+1], "日") == "2" && strings.TrimSuffix(row[i+2], "日") == "3" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 指定した値のセルがあるか
+func hasCell(rows [][]string, value string) bool {
+	for _, row := range rows {
+		for _, v := range row {
+			if v == value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 見出しの行から Spreadsheets.Get の Data と同じ形のシートを作成
+func templateSheet(id int64, title string, rows ...[]string) *sheets.Sheet {
+	var rowData []*sheets.RowData
+	for _, row := range rows {
+		var values []*sheets.CellData
+		for _, v := range row {
+			values = append(values, &sheets.CellData{FormattedValue: v})
+		}
+		rowData = append(rowData, &sheets.RowData{Values: values})
+	}
+	return &sheets.Sheet{
+		Properties: &sheets.SheetProperties{
+			SheetId:        id,
+			Title:          title,
+			SheetType:      "GRID",
+			GridProperties: &sheets.GridProperties{RowCount: 20, ColumnCount: 33},
+		},
+		Data: []*sheets.GridData{{RowData: rowData}},
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	schedule := templateSheet(1, "勤務表", []string{"2024"}, nil, []string{"4"}, []string{"名前", "1", "2", "3", "合計"})
+	noTotals := templateSheet(2, "勤務表2", []string{"2024"}, nil, []string{"4"}, []string{"名前", "1日", "2日", "3日"})
+	summary := templateSheet(3, "集計", []string{"社員", "合計時間"})
+	notes := templateSheet(4, "メモ")
+	notes.Properties.Hidden = true
+
+	tests := []struct {
+		name         string
+		sheets       []*sheets.Sheet
+		wantProblems []string
+		wantWarnings []string
+	}{
+		{name: "schedule only", sheets: []*sheets.Sheet{schedule}},
+		{
+			name:         "schedule with summary and hidden notes",
+			sheets:       []*sheets.Sheet{schedule, summary, notes},
+			wantWarnings: []string{`"集計"`, `"メモ"`},
+		},
+		{
+			name:         "schedule without totals",
+			sheets:       []*sheets.Sheet{noTotals, summary},
+			wantProblems: []string{`"勤務表2": no totals column header`},
+			wantWarnings: []string{`"集計"`},
+		},
+		{
+			name:         "no schedule sheet",
+			sheets:       []*sheets.Sheet{summary},
+			wantProblems: []string{"template has no schedule sheet"},
+			wantWarnings: []string{`"集計"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSheets("unused", 1, 1)
+			fake.spreadsheet.Sheets = tt.sheets
+			problems, warnings, err := validateTemplate(context.Background(), fake.service(t), "fake")
+			if err != nil {
+				t.Fatalf("validateTemplate: %v", err)
+			}
+			checkMessages(t, "problems", problems, tt.wantProblems)
+			checkMessages(t, "warnings", warnings, tt.wantWarnings)
+		})
+	}
+}
+
+// got の各メッセージが want の対応する部分文字列を含むかを確認
+func checkMessages(t *testing.T, kind string, got []string, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s = %q, want %d messages containing %q", kind, got, len(want), want)
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("%s[%d] = %q, want it to contain %q", kind, i, got[i], want[i])
+		}
+	}
+}
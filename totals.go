@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// 社員ごとの週の小計を合計列の右に書き込む
+// A列が社員名、B列から1日ずつ日付の列、その右が合計列のレイアウトを前提とする
+// dataStartRow は社員の最初の行（1始まり）で、その上の行に週の見出しを書く
+// 週は日曜始まりで、月初・月末の週は月内の日だけを合計する
+func insertWeeklySubtotals(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetName string, year int, month int, dataStartRow int) (UpdateResult, error) {
+	if dataStartRow < 1 {
+		return UpdateResult{}, fmt.Errorf("data start row must be 1 or greater, got %d", dataStartRow)
+	}
+	weeks, err := monthWeeks(year, month)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	days := weeks[len(weeks)-1][1]
+
+	props, err := getSheetProperties(ctx, srv, spreadsheetId, sheetName)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	if props.GridProperties == nil {
+		return UpdateResult{}, fmt.Errorf("sheet %q is not a grid sheet", props.Title)
+	}
+
+	// 社員名が途切れる行までをデータとする
+	endRow, err := firstEmptyRow(ctx, srv, spreadsheetId, fmt.Sprintf("%s!A%d:A", quoteSheetName(props.Title), dataStartRow))
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	if endRow <= dataStartRow {
+		return UpdateResult{}, fmt.Errorf("sheet %q has no employee rows from row %d", props.Title, dataStartRow)
+	}
+
+	// 社員名・日付・合計の列の右から週の数だけ使う
+	firstColumn := int64(days + 2)
+	needed := firstColumn + int64(len(weeks))
+	if props.GridProperties.ColumnCount < needed {
+		appendRequest := &sheets.Request{
+			AppendDimension: &sheets.AppendDimensionRequest{
+				SheetId:   props.SheetId,
+				Dimension: "COLUMNS",
+				Length:    needed - props.GridProperties.ColumnCount,
+			},
+		}
+		if _, err := batchUpdate(ctx, srv, spreadsheetId, []*sheets.Request{appendRequest}); err != nil {
+			return UpdateResult{}, err
+		}
+	}
+
+	topRow := int64(dataStartRow - 1)
+	var values [][]interface{}
+	if dataStartRow > 1 {
+		topRow--
+		header := make([]interface{}, len(weeks))
+		for i, week := range weeks {
+			header[i] = fmt.Sprintf("第%d週(%d-%d)", i+1, week[0], week[1])
+		}
+		values = append(values, header)
+	}
+	for row := dataStartRow; row < endRow; row++ {
+		subtotals := make([]interface{}, len(weeks))
+		for i, week := range weeks {
+			// 日にち d の列は0始まりの d 列目（B列が1日）
+			subtotals[i] = fmt.Sprintf("=SUM(%s%d:%s%d)", columnLetters(int64(week[0])), row, columnLetters(int64(week[1])), row)
+		}
+		values = append(values, subtotals)
+	}
+
+	a1 := cellA1(props.Title, topRow, firstColumn) + ":" +
+		cellA1("", topRow+int64(len(values))-1, firstColumn+int64(len(weeks))-1)
+	valueRange := &sheets.ValueRange{
+		Range:          a1,
+		Values:         values,
+		MajorDimension: "ROWS",
+	}
+
+	resp, err := srv.Spreadsheets.Values.Update(spreadsheetId, a1, valueRange).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	return updateResultFrom(resp), nil
+}
+
+// 日付の列ごとの合計（その日の人員の確認用）を totalRow 行目（1始まり）に書き込む
+// dataRange は "B6:AF20" のような日付の列と社員の行の範囲（シート名は sheetName で指定）
+func appendColumnTotals(ctx context.Context, srv *sheets.Service, spreadsheetId string, sheetName string, dataRange string, totalRow int) (UpdateResult, error) {
+	r, err := parseA1Range(dataRange)
+	if err != nil {
+		return UpdateResult{}, err
+	}
+	if r.Sheet != "" || !r.hasRows || !r.hasColumns || r.openEndRow {
+		return UpdateResult{}, fmt.Errorf("data range %q must be a cell range without a sheet name, such as B6:AF20", dataRange)
+	}
+	if totalRow < 1 {
+		return UpdateResult{}, fmt.Errorf("total row must be 1 or greater, got %d", totalRow)
+	}
+	if row := int64(totalRow - 1); r.StartRow <= row && row < r.EndRow {
+		return UpdateResult{}, fmt.Errorf("total row %d is inside data range %q", totalRow, dataRange)
+	}
+
+	totals := make([]interface{}, 0, r.EndColumn-r.StartColumn)
+	for col := r.StartColumn; col < r.EndColumn; col++ {
+		totals = append(totals, fmt.Sprintf("=SUM(%s:%s)", cellA1("", r.StartRow, col), cellA1("", r.EndRow-1, col)))
+	}
+
+	a1 := cellA1(sheetName, int64(totalRow-1), r.StartColumn) + ":" + cellA1("", int64(totalRow-1), r.EndColumn-1)
+	if err := validateRange(ctx, srv, spreadsheetId, a1); err != nil {
+		return UpdateResult{}, err
+	}
+	valueRange := &sheets.ValueRange{
+		Range:          a1,
+		Values:         [][]interface{}{totals},
+		MajorDimension: "ROWS",
+	}
+
+	resp, err := srv.Spreadsheets.Values.Update(spreadsheetId, a1, valueRange).ValueInputOption("USER_ENTERED").Context(ctx).Do()
+	if err != nil {
+		return UpdateResult{}, err
+	}
+
+	return updateResultFrom(resp), nil
+}